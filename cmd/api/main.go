@@ -14,7 +14,9 @@ import (
 	"github.com/plastinin/docrecognizer/internal/adapter/storage"
 	"github.com/plastinin/docrecognizer/internal/config"
 	"github.com/plastinin/docrecognizer/internal/usecase"
+	"github.com/plastinin/docrecognizer/migrations"
 	"github.com/plastinin/docrecognizer/pkg/logger"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
 	"go.uber.org/zap"
 
 	apphttp "github.com/plastinin/docrecognizer/internal/adapter/http"
@@ -31,6 +33,14 @@ func main() {
 	log := logger.Must(cfg.Log.Level, cfg.Log.Format)
 	defer log.Sync()
 
+	// Подкоманда migrate: применяем миграции и выходим
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(context.Background(), cfg, log, os.Args[2:]); err != nil {
+			log.Fatal("Migration failed", zap.Error(err))
+		}
+		return
+	}
+
 	log.Info("Starting docrecognizer API",
 		zap.String("host", cfg.Server.Host),
 		zap.Int("port", cfg.Server.Port),
@@ -40,6 +50,22 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Инициализируем трассировку
+	shutdownTracing, err := tracing.Init(ctx, tracing.Options{
+		ServiceName: "docrecognizer-api",
+		Enabled:     cfg.Tracing.Enabled,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatal("Failed to init tracing", zap.Error(err))
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error("Failed to shutdown tracing", zap.Error(err))
+		}
+	}()
+
 	// Инициализируем PostgreSQL
 	dbPool, err := repository.NewPostgresPool(ctx, cfg.Database)
 	if err != nil {
@@ -48,35 +74,81 @@ func main() {
 	defer dbPool.Close()
 	log.Info("Connected to PostgreSQL")
 
-	// Инициализируем S3 Storage
-	s3Storage, err := storage.NewS3Storage(ctx, cfg.S3)
+	// Применяем миграции при старте, если включено
+	if cfg.Database.AutoMigrate {
+		migrator, err := repository.NewMigrator(dbPool, migrations.FS, log)
+		if err != nil {
+			log.Fatal("Failed to load migrations", zap.Error(err))
+		}
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatal("Failed to apply migrations", zap.Error(err))
+		}
+		log.Info("Migrations applied", zap.Int("count", applied))
+	}
+
+	// Инициализируем файловое хранилище
+	fileStorage, err := storage.New(ctx, cfg.Storage, cfg.S3)
+	if err != nil {
+		log.Fatal("Failed to initialize file storage", zap.Error(err))
+	}
+
+	// Локальное хранилище отдаёт и принимает файлы через API
+	var fileHandler *handler.FileHandler
+	if localStorage, ok := fileStorage.(*storage.LocalStorage); ok {
+		fileHandler = handler.NewFileHandler(localStorage, storage.FilesPathPrefix, cfg.Upload.MaxSize, log)
+	}
+
+	// Шифрование включается поверх выбранного хранилища
+	fileStorage, err = storage.WithEncryption(fileStorage, cfg.Encryption)
 	if err != nil {
-		log.Fatal("Failed to connect to S3", zap.Error(err))
+		log.Fatal("Failed to initialize storage encryption", zap.Error(err))
 	}
-	log.Info("Connected to S3",
-		zap.String("endpoint", cfg.S3.Endpoint),
-		zap.String("bucket", cfg.S3.Bucket),
+	log.Info("File storage initialized",
+		zap.String("backend", cfg.Storage.Backend),
+		zap.Bool("encryption", cfg.Encryption.Enabled),
 	)
 
+	// Пароли документов хранятся зашифрованными мастер-ключами
+	secrets, err := storage.NewSecretSealer(cfg.Encryption)
+	if err != nil {
+		log.Fatal("Failed to initialize secrets encryption", zap.Error(err))
+	}
+	log.Info("Document passwords", zap.Bool("enabled", secrets != nil))
+
 	// Инициализируем Queue Producer
-	queueProducer := queue.NewTaskProducer(cfg.Redis)
+	queueProducer := queue.NewTaskProducer(cfg.Redis, cfg.Worker)
 	defer queueProducer.Close()
 	log.Info("Connected to Redis",
 		zap.String("addr", cfg.Redis.Addr()),
 	)
 
+	// Инициализируем инспектор очереди
+	queueInspector := queue.NewQueueInspector(cfg.Redis, cfg.Worker.Queue)
+	defer queueInspector.Close()
+
 	// Инициализируем репозитории
 	taskRepo := repository.NewTaskRepository(dbPool)
 
 	// Инициализируем use cases
-	taskUC := usecase.NewTaskUseCase(taskRepo, s3Storage, queueProducer, log)
+	taskUC := usecase.NewTaskUseCase(taskRepo, fileStorage, queueProducer, secrets, usecase.UploadOptions{
+		MaxSize:    cfg.Upload.MaxSize,
+		PresignTTL: cfg.Upload.PresignTTL,
+	}, log)
+	queueUC := usecase.NewQueueUseCase(taskRepo, queueInspector, log)
 
 	// Инициализируем handlers
 	taskHandler := handler.NewTaskHandler(taskUC, log)
-	healthHandler := handler.NewHealthHandler()
+	healthHandler := handler.NewHealthHandler(cfg.Server.HealthTimeout, log,
+		handler.HealthCheck{Name: "postgres", Critical: true, Check: dbPool.Ping},
+		handler.HealthCheck{Name: "redis", Critical: true, Check: queueProducer.CheckHealth},
+		handler.HealthCheck{Name: cfg.Storage.Backend, Critical: true, Check: fileStorage.CheckHealth},
+	)
+	adminHandler := handler.NewAdminHandler(queueUC, log)
 
 	// Создаём роутер
-	router := apphttp.NewRouter(taskHandler, healthHandler, log)
+	router := apphttp.NewRouter(taskHandler, healthHandler, adminHandler, fileHandler, cfg.Server.AdminToken, log)
+	log.Info("Queue admin API", zap.Bool("enabled", cfg.Server.AdminToken != ""))
 
 	// Создаём HTTP сервер
 	server := &http.Server{
@@ -112,4 +184,4 @@ func main() {
 	}
 
 	log.Info("Server stopped")
-}
\ No newline at end of file
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/plastinin/docrecognizer/internal/adapter/repository"
+	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/migrations"
+	"go.uber.org/zap"
+)
+
+const migrateUsage = "usage: api migrate up | down [N] | status"
+
+// runMigrate выполняет подкоманду migrate
+func runMigrate(ctx context.Context, cfg *config.Config, log *zap.Logger, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	dbPool, err := repository.NewPostgresPool(ctx, cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbPool.Close()
+
+	migrator, err := repository.NewMigrator(dbPool, migrations.FS, log)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info("Migrations applied", zap.Int("count", applied))
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q: %s", args[1], migrateUsage)
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		log.Info("Migrations reverted", zap.Int("count", reverted))
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "version: %d, dirty: %t\n", status.Version, status.Dirty)
+		for _, m := range status.Migrations {
+			state := "pending"
+			if m.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(os.Stdout, "%06d_%s\t%s\n", m.Version, m.Name, state)
+		}
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
@@ -2,18 +2,29 @@ package main
 
 import (
 	"context"
+	"errors"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/plastinin/docrecognizer/internal/adapter/cache"
+	"github.com/plastinin/docrecognizer/internal/adapter/fetcher"
+	"github.com/plastinin/docrecognizer/internal/adapter/http/handler"
 	"github.com/plastinin/docrecognizer/internal/adapter/llm"
 	"github.com/plastinin/docrecognizer/internal/adapter/queue"
 	"github.com/plastinin/docrecognizer/internal/adapter/repository"
 	"github.com/plastinin/docrecognizer/internal/adapter/storage"
+	"github.com/plastinin/docrecognizer/internal/adapter/templates"
 	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/internal/domain"
 	"github.com/plastinin/docrecognizer/internal/usecase"
 	"github.com/plastinin/docrecognizer/pkg/logger"
+	"github.com/plastinin/docrecognizer/pkg/metrics"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
 	"go.uber.org/zap"
+
+	apphttp "github.com/plastinin/docrecognizer/internal/adapter/http"
 )
 
 func main() {
@@ -30,11 +41,29 @@ func main() {
 	log.Info("Starting docrecognizer worker",
 		zap.String("ollama_host", cfg.Ollama.Host),
 		zap.String("ollama_model", cfg.Ollama.Model),
+		zap.Int("concurrency", cfg.Worker.Concurrency),
+		zap.Any("queues", cfg.Worker.Queues),
 	)
 
 	// Контекст для инициализации
 	ctx := context.Background()
 
+	// Инициализируем трассировку
+	shutdownTracing, err := tracing.Init(ctx, tracing.Options{
+		ServiceName: "docrecognizer-worker",
+		Enabled:     cfg.Tracing.Enabled,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Fatal("Failed to init tracing", zap.Error(err))
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error("Failed to shutdown tracing", zap.Error(err))
+		}
+	}()
+
 	// Инициализируем PostgreSQL
 	dbPool, err := repository.NewPostgresPool(ctx, cfg.Database)
 	if err != nil {
@@ -43,18 +72,50 @@ func main() {
 	defer dbPool.Close()
 	log.Info("Connected to PostgreSQL")
 
-	// Инициализируем S3 Storage
-	s3Storage, err := storage.NewS3Storage(ctx, cfg.S3)
+	// Инициализируем файловое хранилище
+	fileStorage, err := storage.New(ctx, cfg.Storage, cfg.S3)
 	if err != nil {
-		log.Fatal("Failed to connect to S3", zap.Error(err))
+		log.Fatal("Failed to initialize file storage", zap.Error(err))
 	}
-	log.Info("Connected to S3",
-		zap.String("endpoint", cfg.S3.Endpoint),
-		zap.String("bucket", cfg.S3.Bucket),
+	fileStorage, err = storage.WithEncryption(fileStorage, cfg.Encryption)
+	if err != nil {
+		log.Fatal("Failed to initialize storage encryption", zap.Error(err))
+	}
+	log.Info("File storage initialized",
+		zap.String("backend", cfg.Storage.Backend),
+		zap.Bool("encryption", cfg.Encryption.Enabled),
 	)
 
+	// Пароли документов хранятся зашифрованными мастер-ключами
+	secrets, err := storage.NewSecretSealer(cfg.Encryption)
+	if err != nil {
+		log.Fatal("Failed to initialize secrets encryption", zap.Error(err))
+	}
+	log.Info("Document passwords", zap.Bool("enabled", secrets != nil))
+
+	// Загружаем промпты: встроенные заменяются промптами из каталога и БД
+	prompts, err := llm.NewPromptSet()
+	if err != nil {
+		log.Fatal("Failed to load builtin prompts", zap.Error(err))
+	}
+	if err := prompts.LoadDir(cfg.Prompts.Dir); err != nil {
+		log.Fatal("Failed to load prompts", zap.String("dir", cfg.Prompts.Dir), zap.Error(err))
+	}
+	if cfg.Prompts.FromDB {
+		stored, err := repository.NewPromptRepository(dbPool).List(ctx)
+		if err != nil {
+			log.Fatal("Failed to load prompts from database", zap.Error(err))
+		}
+		for _, prompt := range stored {
+			if err := prompts.Add(prompt.Name, prompt.Body); err != nil {
+				log.Fatal("Failed to load prompt from database", zap.String("name", prompt.Name), zap.Error(err))
+			}
+		}
+	}
+	log.Info("Prompts loaded", zap.Int("count", prompts.Len()))
+
 	// Инициализируем Ollama клиент
-	ollamaClient := llm.NewOllamaClient(cfg.Ollama, log)
+	ollamaClient := llm.NewOllamaClient(cfg.Ollama, prompts, log)
 
 	// Проверяем доступность Ollama
 	if err := ollamaClient.CheckHealth(ctx); err != nil {
@@ -75,11 +136,55 @@ func main() {
 	// Инициализируем репозитории
 	taskRepo := repository.NewTaskRepository(dbPool)
 
+	retentionRepo := repository.NewRetentionRepository(dbPool)
+
+	// Инициализируем загрузчик документов по URL
+	documentFetcher := fetcher.NewHTTPFetcher(cfg.Upload)
+
+	// Инициализируем кэш результатов распознавания
+	var recognitionCache usecase.RecognitionCache
+	if cfg.Cache.Enabled {
+		redisCache := cache.NewRecognitionCache(cfg.Redis, cfg.Cache.TTL)
+		defer redisCache.Close()
+		recognitionCache = redisCache
+		log.Info("Recognition cache enabled", zap.Duration("ttl", cfg.Cache.TTL))
+	}
+
+	// Загружаем шаблоны документов
+	templateRegistry, err := templates.LoadFile(cfg.Templates.File)
+	if err != nil {
+		log.Fatal("Failed to load templates", zap.Error(err))
+	}
+	log.Info("Templates loaded", zap.Int("count", templateRegistry.Len()))
+
+	inputMode, err := domain.ParseInputMode(cfg.Render.InputMode)
+	if err != nil {
+		log.Fatal("Invalid PDF input mode", zap.String("input_mode", cfg.Render.InputMode), zap.Error(err))
+	}
+
+	// Дочерние задачи разделённых документов воркер ставит в очередь сам
+	queueProducer := queue.NewTaskProducer(cfg.Redis, cfg.Worker)
+	defer queueProducer.Close()
+
 	// Инициализируем use cases
-	recognitionUC := usecase.NewRecognitionUseCase(taskRepo, s3Storage, ollamaClient, pdfConverter, log)
+	recognitionUC := usecase.NewRecognitionUseCase(taskRepo, fileStorage, queueProducer, ollamaClient, pdfConverter, documentFetcher, recognitionCache, templateRegistry, secrets, usecase.RecognitionOptions{
+		MemoryLimit: cfg.Worker.TaskMemoryLimit,
+		TempDir:     cfg.Worker.TempDir,
+		Render: domain.RenderOptions{
+			DPI:       cfg.Render.DPI,
+			Pages:     cfg.Render.Pages,
+			MaxWidth:  cfg.Render.MaxWidth,
+			MaxHeight: cfg.Render.MaxHeight,
+		},
+		MaxPages:      cfg.Render.MaxPages,
+		InputMode:     inputMode,
+		MinConfidence: cfg.Templates.MinConfidence,
+		SplitMaxPages: cfg.Render.SplitMaxPages,
+		Language:      cfg.Prompts.Language,
+	}, log)
 
 	// Инициализируем consumer
-	consumer := queue.NewTaskConsumer(cfg.Redis, recognitionUC, log)
+	consumer := queue.NewTaskConsumer(cfg.Redis, cfg.Worker, recognitionUC, log)
 
 	// Запускаем consumer в горутине
 	go func() {
@@ -88,6 +193,57 @@ func main() {
 		}
 	}()
 
+	// Запускаем периодическую очистку по политикам хранения
+	retentionCtx, retentionCancel := context.WithCancel(ctx)
+	defer retentionCancel()
+	if cfg.Retention.Enabled {
+		policies, err := domain.ParseRetentionPolicies(cfg.Retention.Policies)
+		if err != nil {
+			log.Fatal("Invalid retention policies", zap.Error(err))
+		}
+
+		retentionUC := usecase.NewRetentionUseCase(retentionRepo, fileStorage, policies, cfg.Retention.BatchSize, log)
+		go retentionUC.Start(retentionCtx, cfg.Retention.Interval)
+
+		log.Info("Retention enabled",
+			zap.Strings("policies", cfg.Retention.Policies),
+			zap.Duration("interval", cfg.Retention.Interval),
+		)
+	}
+
+	// Регистрируем метрики очередей
+	queueMetrics := queue.NewMetricsCollector(cfg.Redis, cfg.Worker, log)
+	defer queueMetrics.Close()
+	metrics.MustRegister(queueMetrics)
+
+	// Health check зависимостей воркера
+	healthHandler := handler.NewHealthHandler(cfg.Server.HealthTimeout, log,
+		handler.HealthCheck{Name: "postgres", Critical: true, Check: dbPool.Ping},
+		handler.HealthCheck{Name: "redis", Critical: true, Check: consumer.CheckHealth},
+		handler.HealthCheck{Name: cfg.Storage.Backend, Critical: true, Check: fileStorage.CheckHealth},
+		handler.HealthCheck{Name: "ollama", Critical: true, Check: func(ctx context.Context) error {
+			if err := ollamaClient.CheckHealth(ctx); err != nil {
+				return err
+			}
+			return ollamaClient.CheckModel(ctx)
+		}},
+	)
+
+	// Служебный HTTP сервер воркера
+	server := &http.Server{
+		Addr:    cfg.Worker.HTTPAddr(),
+		Handler: apphttp.NewWorkerRouter(healthHandler),
+	}
+
+	go func() {
+		log.Info("Worker HTTP server starting",
+			zap.String("addr", cfg.Worker.HTTPAddr()),
+		)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Worker HTTP server failed", zap.Error(err))
+		}
+	}()
+
 	log.Info("Worker started, waiting for tasks...")
 
 	// Ожидаем сигнал завершения
@@ -97,8 +253,17 @@ func main() {
 
 	log.Info("Shutting down worker...")
 
-	// Останавливаем consumer
+	// Останавливаем consumer и очистку
 	consumer.Stop()
+	retentionCancel()
+
+	// Останавливаем HTTP сервер
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer shutdownCancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Error("Worker HTTP server forced to shutdown", zap.Error(err))
+	}
 
 	log.Info("Worker stopped")
-}
\ No newline at end of file
+}
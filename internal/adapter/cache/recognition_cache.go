@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// keyPrefix префикс ключей кэша результатов распознавания в Redis
+const keyPrefix = "docrecognizer:recognition:"
+
+// RecognitionCache кэш результатов распознавания в Redis
+type RecognitionCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewRecognitionCache создаёт новый экземпляр RecognitionCache
+func NewRecognitionCache(cfg config.RedisConfig, ttl time.Duration) *RecognitionCache {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr(),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	return &RecognitionCache{
+		client: client,
+		ttl:    ttl,
+	}
+}
+
+// Get возвращает закэшированный результат; ok=false, если записи нет
+func (c *RecognitionCache) Get(ctx context.Context, key string) (map[string]any, bool, error) {
+	data, err := c.client.Get(ctx, keyPrefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get cached result: %w", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cached result: %w", err)
+	}
+
+	return result, true, nil
+}
+
+// Set сохраняет результат распознавания с TTL
+func (c *RecognitionCache) Set(ctx context.Context, key string, result map[string]any) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+
+	if err := c.client.Set(ctx, keyPrefix+key, data, c.ttl).Err(); err != nil {
+		return fmt.Errorf("failed to cache result: %w", err)
+	}
+
+	return nil
+}
+
+// Close закрывает соединение с Redis
+func (c *RecognitionCache) Close() error {
+	return c.client.Close()
+}
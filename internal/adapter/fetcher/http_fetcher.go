@@ -0,0 +1,189 @@
+package fetcher
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxRedirects максимальное количество редиректов при скачивании
+const maxRedirects = 5
+
+// sniffLen количество байт для определения типа содержимого
+const sniffLen = 512
+
+// cgnatPrefix адреса Carrier-Grade NAT (RFC 6598), не маршрутизируемые в интернете
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// HTTPFetcher скачивает документы по URL с ограничениями по размеру и времени
+// и защитой от SSRF: соединения с внутренними адресами запрещены
+type HTTPFetcher struct {
+	client  *http.Client
+	maxSize int64
+}
+
+// NewHTTPFetcher создаёт новый экземпляр HTTPFetcher
+func NewHTTPFetcher(cfg config.UploadConfig) *HTTPFetcher {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+	}
+	if !cfg.FetchAllowPrivate {
+		// Проверяем адрес уже после DNS резолвинга, что защищает и от DNS rebinding
+		dialer.Control = denyInternalAddress
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil, // Прокси из окружения обошёл бы проверку адресов
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+	}
+
+	return &HTTPFetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   cfg.FetchTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("%w: too many redirects", domain.ErrInvalidSourceURL)
+				}
+				if _, err := domain.ValidateSourceURL(req.URL.String()); err != nil {
+					return err
+				}
+				return nil
+			},
+		},
+		maxSize: cfg.MaxSize,
+	}
+}
+
+// Fetch скачивает документ. Тело ответа ограничено maxSize: при превышении
+// чтение вернёт domain.ErrFileTooLarge. Тип содержимого определяется по
+// заголовку, сигнатуре файла или расширению
+func (f *HTTPFetcher) Fetch(ctx context.Context, sourceURL string) (io.ReadCloser, *domain.FileInfo, error) {
+	ctx, span := tracing.Start(ctx, "http.Fetch", attribute.String("url.full", sourceURL))
+	defer span.End()
+
+	u, err := domain.ValidateSourceURL(sourceURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, nil, fmt.Errorf("failed to fetch document: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("source returned status %d", resp.StatusCode)
+	}
+
+	if resp.ContentLength > f.maxSize {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("%w: %d bytes", domain.ErrFileTooLarge, resp.ContentLength)
+	}
+
+	body := bufio.NewReaderSize(&limitedReader{r: resp.Body, remaining: f.maxSize}, sniffLen)
+	info := &domain.FileInfo{
+		Size:        resp.ContentLength,
+		ContentType: detectContentType(resp.Header.Get("Content-Type"), body, domain.FileNameFromURL(resp.Request.URL)),
+	}
+	span.SetAttributes(attribute.String("content_type", info.ContentType))
+
+	return &readCloser{Reader: body, Closer: resp.Body}, info, nil
+}
+
+// detectContentType определяет тип содержимого. Серверы часто отдают
+// application/octet-stream, поэтому неподдерживаемый заголовок перепроверяется
+func detectContentType(header string, body *bufio.Reader, fileName string) string {
+	if mediaType, _, err := mime.ParseMediaType(header); err == nil && domain.ValidateContentType(mediaType) == nil {
+		return mediaType
+	}
+
+	if head, _ := body.Peek(sniffLen); len(head) > 0 {
+		sniffed := strings.Split(http.DetectContentType(head), ";")[0]
+		if domain.ValidateContentType(sniffed) == nil {
+			return sniffed
+		}
+	}
+
+	if ct, err := domain.ContentTypeFromFileName(fileName); err == nil {
+		return ct
+	}
+
+	return header
+}
+
+// denyInternalAddress запрещает соединения с loopback, приватными,
+// link-local и прочими немаршрутизируемыми адресами
+func denyInternalAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("%w: %s", domain.ErrForbiddenSourceAddress, host)
+	}
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() ||
+		cgnatPrefix.Contains(addr) {
+		return fmt.Errorf("%w: %s", domain.ErrForbiddenSourceAddress, host)
+	}
+
+	return nil
+}
+
+// limitedReader возвращает ErrFileTooLarge при превышении лимита,
+// в отличие от io.LimitReader, который молча обрезает данные
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, domain.ErrFileTooLarge
+	}
+	// Читаем на байт больше лимита, чтобы отличить файл ровно в лимит от большего
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, domain.ErrFileTooLarge
+	}
+	return n, err
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,94 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/plastinin/docrecognizer/internal/domain"
+)
+
+// QueueInfoResponse ответ с состоянием очереди
+type QueueInfoResponse struct {
+	Queue     string `json:"queue"`
+	Size      int    `json:"size"`
+	Pending   int    `json:"pending"`
+	Active    int    `json:"active"`
+	Scheduled int    `json:"scheduled"`
+	Retry     int    `json:"retry"`
+	Archived  int    `json:"archived"`
+	Completed int    `json:"completed"`
+	Processed int    `json:"processed"`
+	Failed    int    `json:"failed"`
+	Paused    bool   `json:"paused"`
+	LatencyMs int64  `json:"latency_ms"`
+}
+
+// QueueInfoFromDomain конвертирует состояние очереди в DTO
+func QueueInfoFromDomain(info *domain.QueueInfo) *QueueInfoResponse {
+	return &QueueInfoResponse{
+		Queue:     info.Queue,
+		Size:      info.Size,
+		Pending:   info.Pending,
+		Active:    info.Active,
+		Scheduled: info.Scheduled,
+		Retry:     info.Retry,
+		Archived:  info.Archived,
+		Completed: info.Completed,
+		Processed: info.Processed,
+		Failed:    info.Failed,
+		Paused:    info.Paused,
+		LatencyMs: info.Latency.Milliseconds(),
+	}
+}
+
+// QueueTaskResponse ответ с информацией о задаче в очереди
+type QueueTaskResponse struct {
+	ID            string     `json:"id"`
+	TaskID        string     `json:"task_id"`
+	Type          string     `json:"type"`
+	Queue         string     `json:"queue"`
+	State         string     `json:"state"`
+	MaxRetry      int        `json:"max_retry"`
+	Retried       int        `json:"retried"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastFailedAt  *time.Time `json:"last_failed_at,omitempty"`
+	NextProcessAt *time.Time `json:"next_process_at,omitempty"`
+}
+
+// QueueTaskFromDomain конвертирует задачу очереди в DTO
+func QueueTaskFromDomain(task *domain.QueueTask) *QueueTaskResponse {
+	return &QueueTaskResponse{
+		ID:            task.ID,
+		TaskID:        task.TaskID,
+		Type:          task.Type,
+		Queue:         task.Queue,
+		State:         task.State.String(),
+		MaxRetry:      task.MaxRetry,
+		Retried:       task.Retried,
+		LastError:     task.LastError,
+		LastFailedAt:  task.LastFailedAt,
+		NextProcessAt: task.NextProcessAt,
+	}
+}
+
+// QueueTaskListResponse ответ со списком задач очереди
+type QueueTaskListResponse struct {
+	Tasks    []*QueueTaskResponse `json:"tasks"`
+	State    string               `json:"state"`
+	Page     int                  `json:"page"`
+	PageSize int                  `json:"page_size"`
+}
+
+// QueueTaskListFromDomain конвертирует список задач очереди в DTO
+func QueueTaskListFromDomain(tasks []*domain.QueueTask, state domain.QueueTaskState, pagination domain.Pagination) *QueueTaskListResponse {
+	items := make([]*QueueTaskResponse, len(tasks))
+	for i, task := range tasks {
+		items[i] = QueueTaskFromDomain(task)
+	}
+
+	return &QueueTaskListResponse{
+		Tasks:    items,
+		State:    state.String(),
+		Page:     pagination.Page,
+		PageSize: pagination.PageSize,
+	}
+}
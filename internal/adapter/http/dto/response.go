@@ -12,4 +12,4 @@ func NewErrorResponse(err string, message string) *ErrorResponse {
 		Error:   err,
 		Message: message,
 	}
-}
\ No newline at end of file
+}
@@ -12,43 +12,125 @@ type CreateTaskRequest struct {
 	Schema []string `json:"schema"` // Поля для извлечения
 }
 
+// CreateTaskJSONRequest запрос на создание задачи с файлом в base64
+// для клиентов, которые не умеют формировать multipart запросы
+type CreateTaskJSONRequest struct {
+	FileName    string   `json:"file_name"`
+	ContentType string   `json:"content_type,omitempty"` // По умолчанию определяется по расширению
+	Data        string   `json:"data"`                   // Содержимое файла в base64
+	Schema      []string `json:"schema"`
+	Template    string   `json:"template,omitempty"`
+	Tenant      string   `json:"tenant,omitempty"`
+	BatchID     string   `json:"batch_id,omitempty"`
+	Dedupe      string   `json:"dedupe,omitempty"`
+	CacheBypass bool     `json:"cache_bypass,omitempty"`
+
+	Render    *domain.RenderOptions `json:"render,omitempty"`     // Параметры рендеринга PDF
+	InputMode string                `json:"input_mode,omitempty"` // image, text или hybrid
+	Password  string                `json:"password,omitempty"`   // Пароль зашифрованного PDF
+	Classify  bool                  `json:"classify,omitempty"`   // Определить тип документа; schema — запасная схема
+	Split     string                `json:"split,omitempty"`      // blank или classify — разделить файл на документы
+}
+
 // TaskResponse ответ с информацией о задаче
 type TaskResponse struct {
-	ID          string         `json:"id"`
-	Status      string         `json:"status"`
-	FileName    string         `json:"file_name"`
-	ContentType string         `json:"content_type"`
-	Schema      []string       `json:"schema"`
-	Result      map[string]any `json:"result,omitempty"`
-	Error       string         `json:"error,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	CompletedAt *time.Time     `json:"completed_at,omitempty"`
+	ID             string                `json:"id"`
+	Status         string                `json:"status"`
+	FileName       string                `json:"file_name"`
+	ContentType    string                `json:"content_type"`
+	Schema         []string              `json:"schema"`
+	Template       string                `json:"template,omitempty"`
+	Tenant         string                `json:"tenant,omitempty"`
+	BatchID        *string               `json:"batch_id,omitempty"`
+	Split          string                `json:"split,omitempty"`
+	ParentID       *string               `json:"parent_id,omitempty"` // Задача, из файла которой выделен документ (страницы — render.pages)
+	FileHash       string                `json:"file_hash,omitempty"`
+	DuplicateOf    *string               `json:"duplicate_of,omitempty"`
+	CacheStatus    string                `json:"cache_status,omitempty"`
+	PromptVersion  string                `json:"prompt_version,omitempty"` // Версия промпта, с которым распознан документ
+	SourceURL      string                `json:"source_url,omitempty"`
+	PageCount      int                   `json:"page_count,omitempty"` // Изображения страниц доступны по /tasks/{id}/pages/{n}.png
+	Render         *domain.RenderOptions `json:"render,omitempty"`
+	InputMode      string                `json:"input_mode,omitempty"`
+	Result         map[string]any        `json:"result,omitempty"`
+	Error          string                `json:"error,omitempty"`
+	ErrorCode      string                `json:"error_code,omitempty"` // Например pdf_password_required
+	Classify       bool                  `json:"classify,omitempty"`
+	DocumentType   string                `json:"document_type,omitempty"` // Тип документа, предсказанный классификацией
+	Confidence     float64               `json:"classification_confidence,omitempty"`
+	CreatedAt      time.Time             `json:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at"`
+	CompletedAt    *time.Time            `json:"completed_at,omitempty"`
+	FileDeletedAt  *time.Time            `json:"file_deleted_at,omitempty"`
+	ResultPurgedAt *time.Time            `json:"result_purged_at,omitempty"`
 }
 
 // TaskFromDomain конвертирует доменную модель в DTO
 func TaskFromDomain(task *domain.Task) *TaskResponse {
+	var batchID *string
+	if task.BatchID != nil {
+		id := task.BatchID.String()
+		batchID = &id
+	}
+
+	var duplicateOf *string
+	if task.DuplicateOf != nil {
+		id := task.DuplicateOf.String()
+		duplicateOf = &id
+	}
+
+	var parentID *string
+	if task.ParentID != nil {
+		id := task.ParentID.String()
+		parentID = &id
+	}
+
+	var render *domain.RenderOptions
+	if !task.Render.IsZero() {
+		render = &task.Render
+	}
+
 	return &TaskResponse{
-		ID:          task.ID.String(),
-		Status:      task.Status.String(),
-		FileName:    task.FileName,
-		ContentType: task.ContentType,
-		Schema:      task.Schema,
-		Result:      task.Result,
-		Error:       task.Error,
-		CreatedAt:   task.CreatedAt,
-		UpdatedAt:   task.UpdatedAt,
-		CompletedAt: task.CompletedAt,
+		ID:             task.ID.String(),
+		Status:         task.Status.String(),
+		FileName:       task.FileName,
+		ContentType:    task.ContentType,
+		Schema:         task.Schema,
+		Template:       task.Template,
+		Tenant:         task.Tenant,
+		BatchID:        batchID,
+		Split:          task.Split.String(),
+		ParentID:       parentID,
+		FileHash:       task.FileHash,
+		DuplicateOf:    duplicateOf,
+		CacheStatus:    task.CacheStatus.String(),
+		PromptVersion:  task.PromptVersion,
+		SourceURL:      task.SourceURL,
+		PageCount:      len(task.PageKeys),
+		Render:         render,
+		InputMode:      task.InputMode.String(),
+		Result:         task.Result,
+		Error:          task.Error,
+		ErrorCode:      task.ErrorCode,
+		Classify:       task.Classify,
+		DocumentType:   task.DocumentType,
+		Confidence:     task.ClassificationConfidence,
+		CreatedAt:      task.CreatedAt,
+		UpdatedAt:      task.UpdatedAt,
+		CompletedAt:    task.CompletedAt,
+		FileDeletedAt:  task.FileDeletedAt,
+		ResultPurgedAt: task.ResultPurgedAt,
 	}
 }
 
 // TaskListResponse ответ со списком задач
 type TaskListResponse struct {
 	Tasks      []*TaskResponse `json:"tasks"`
-	Total      int             `json:"total"`
+	Total      *int            `json:"total,omitempty"` // Для keyset пагинации только с include_total=true
 	Page       int             `json:"page"`
 	PageSize   int             `json:"page_size"`
-	TotalPages int             `json:"total_pages"`
+	TotalPages *int            `json:"total_pages,omitempty"`
+	NextCursor string          `json:"next_cursor,omitempty"` // Курсор следующей страницы (keyset)
 }
 
 // TaskListFromDomain конвертирует результат списка в DTO
@@ -58,16 +140,22 @@ func TaskListFromDomain(result *domain.TaskListResult) *TaskListResponse {
 		tasks[i] = TaskFromDomain(task)
 	}
 
-	totalPages := result.Total / result.Pagination.PageSize
-	if result.Total%result.Pagination.PageSize > 0 {
-		totalPages++
-	}
-
-	return &TaskListResponse{
+	response := &TaskListResponse{
 		Tasks:      tasks,
-		Total:      result.Total,
 		Page:       result.Pagination.Page,
 		PageSize:   result.Pagination.PageSize,
-		TotalPages: totalPages,
+		NextCursor: result.NextCursor,
 	}
-}
\ No newline at end of file
+
+	if result.Pagination.CountTotal {
+		total := result.Total
+		totalPages := total / result.Pagination.PageSize
+		if total%result.Pagination.PageSize > 0 {
+			totalPages++
+		}
+		response.Total = &total
+		response.TotalPages = &totalPages
+	}
+
+	return response
+}
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/plastinin/docrecognizer/internal/domain"
+)
+
+// CreateTaskFromURLRequest запрос на создание задачи по URL документа
+type CreateTaskFromURLRequest struct {
+	URL         string   `json:"url"`
+	FileName    string   `json:"file_name,omitempty"`
+	Schema      []string `json:"schema"`
+	Template    string   `json:"template,omitempty"`
+	Tenant      string   `json:"tenant,omitempty"`
+	BatchID     string   `json:"batch_id,omitempty"`
+	CacheBypass bool     `json:"cache_bypass,omitempty"`
+
+	Render    *domain.RenderOptions `json:"render,omitempty"`     // Параметры рендеринга PDF
+	InputMode string                `json:"input_mode,omitempty"` // image, text или hybrid
+	Password  string                `json:"password,omitempty"`   // Пароль зашифрованного PDF
+	Classify  bool                  `json:"classify,omitempty"`   // Определить тип документа; schema — запасная схема
+	Split     string                `json:"split,omitempty"`      // blank или classify — разделить файл на документы
+}
+
+// CreateUploadRequest запрос на получение presigned URL для прямой загрузки
+type CreateUploadRequest struct {
+	FileName string `json:"file_name"`
+}
+
+// UploadResponse ссылка для прямой загрузки файла в хранилище
+type UploadResponse struct {
+	FileKey   string    `json:"file_key"`
+	UploadURL string    `json:"upload_url"`
+	Method    string    `json:"method"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UploadFromDomain конвертирует presigned загрузку в DTO
+func UploadFromDomain(upload *domain.PresignedUpload) *UploadResponse {
+	return &UploadResponse{
+		FileKey:   upload.FileKey,
+		UploadURL: upload.URL,
+		Method:    upload.Method,
+		ExpiresAt: upload.ExpiresAt,
+	}
+}
+
+// CreateTaskFromUploadRequest запрос на создание задачи по загруженному файлу
+type CreateTaskFromUploadRequest struct {
+	FileKey     string   `json:"file_key"`
+	Schema      []string `json:"schema"`
+	Template    string   `json:"template,omitempty"`
+	Tenant      string   `json:"tenant,omitempty"`
+	BatchID     string   `json:"batch_id,omitempty"`
+	CacheBypass bool     `json:"cache_bypass,omitempty"`
+
+	Render    *domain.RenderOptions `json:"render,omitempty"`     // Параметры рендеринга PDF
+	InputMode string                `json:"input_mode,omitempty"` // image, text или hybrid
+	Password  string                `json:"password,omitempty"`   // Пароль зашифрованного PDF
+	Classify  bool                  `json:"classify,omitempty"`   // Определить тип документа; schema — запасная схема
+	Split     string                `json:"split,omitempty"`      // blank или classify — разделить файл на документы
+}
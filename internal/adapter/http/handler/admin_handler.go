@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/plastinin/docrecognizer/internal/adapter/http/dto"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/internal/usecase"
+	"go.uber.org/zap"
+)
+
+// AdminHandler обработчик административных запросов к очереди
+type AdminHandler struct {
+	queueUC *usecase.QueueUseCase
+	logger  *zap.Logger
+}
+
+// NewAdminHandler создаёт новый AdminHandler
+func NewAdminHandler(queueUC *usecase.QueueUseCase, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		queueUC: queueUC,
+		logger:  logger,
+	}
+}
+
+// QueueInfo возвращает состояние очереди
+// GET /api/v1/admin/queue
+func (h *AdminHandler) QueueInfo(w http.ResponseWriter, r *http.Request) {
+	info, err := h.queueUC.Info(r.Context())
+	if err != nil {
+		h.logger.Error("Failed to get queue info", zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "internal_error", "Failed to get queue info")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, dto.QueueInfoFromDomain(info))
+}
+
+// ListTasks возвращает задачи очереди
+// GET /api/v1/admin/queue/tasks?state=archived&page=1&page_size=20
+func (h *AdminHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	pagination := domain.NewPagination(page, pageSize)
+
+	state := domain.QueueTaskStateArchived
+	if stateStr := r.URL.Query().Get("state"); stateStr != "" {
+		state = domain.QueueTaskState(stateStr)
+	}
+
+	tasks, err := h.queueUC.ListTasks(r.Context(), state, pagination)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidQueueTaskState) {
+			h.respondError(w, http.StatusBadRequest, "invalid_state",
+				"State must be one of: pending, active, scheduled, retry, archived, completed")
+			return
+		}
+		h.logger.Error("Failed to list queue tasks", zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "internal_error", "Failed to list queue tasks")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, dto.QueueTaskListFromDomain(tasks, state, pagination))
+}
+
+// GetTask возвращает задачу очереди по ID
+// GET /api/v1/admin/queue/tasks/{id}
+func (h *AdminHandler) GetTask(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	task, err := h.queueUC.GetTask(r.Context(), id)
+	if err != nil {
+		h.handleQueueError(w, id, "Failed to get queue task", err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, dto.QueueTaskFromDomain(task))
+}
+
+// RequeueTask возвращает архивную задачу в очередь
+// POST /api/v1/admin/queue/tasks/{id}/requeue
+func (h *AdminHandler) RequeueTask(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	task, err := h.queueUC.Requeue(r.Context(), id)
+	if err != nil {
+		h.handleQueueError(w, id, "Failed to requeue task", err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, dto.QueueTaskFromDomain(task))
+}
+
+// DeleteTask удаляет задачу из очереди
+// DELETE /api/v1/admin/queue/tasks/{id}
+func (h *AdminHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.queueUC.DeleteTask(r.Context(), id); err != nil {
+		h.handleQueueError(w, id, "Failed to delete queue task", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PauseQueue приостанавливает обработку очереди
+// POST /api/v1/admin/queue/pause
+func (h *AdminHandler) PauseQueue(w http.ResponseWriter, r *http.Request) {
+	if err := h.queueUC.Pause(r.Context()); err != nil {
+		h.logger.Error("Failed to pause queue", zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "internal_error", "Failed to pause queue")
+		return
+	}
+
+	h.QueueInfo(w, r)
+}
+
+// ResumeQueue возобновляет обработку очереди
+// POST /api/v1/admin/queue/resume
+func (h *AdminHandler) ResumeQueue(w http.ResponseWriter, r *http.Request) {
+	if err := h.queueUC.Resume(r.Context()); err != nil {
+		h.logger.Error("Failed to resume queue", zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "internal_error", "Failed to resume queue")
+		return
+	}
+
+	h.QueueInfo(w, r)
+}
+
+// handleQueueError отправляет ответ для ошибок операций с задачей очереди
+func (h *AdminHandler) handleQueueError(w http.ResponseWriter, id string, msg string, err error) {
+	switch {
+	case errors.Is(err, domain.ErrQueueTaskNotFound):
+		h.respondError(w, http.StatusNotFound, "not_found", "Queue task not found")
+	case errors.Is(err, domain.ErrTaskNotFound):
+		h.respondError(w, http.StatusNotFound, "task_not_found", "Task referenced by queue task not found")
+	case errors.Is(err, domain.ErrTaskFileDeleted):
+		h.respondError(w, http.StatusConflict, "file_deleted", "Task file has been deleted by retention policy")
+	case errors.Is(err, domain.ErrInvalidQueueTaskState):
+		h.respondError(w, http.StatusConflict, "invalid_state", "Operation is not allowed in current task state")
+	default:
+		h.logger.Error(msg, zap.String("queue_task_id", id), zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "internal_error", msg)
+	}
+}
+
+// respondJSON отправляет JSON ответ
+func (h *AdminHandler) respondJSON(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// respondError отправляет ответ с ошибкой
+func (h *AdminHandler) respondError(w http.ResponseWriter, status int, errCode string, message string) {
+	h.respondJSON(w, status, dto.NewErrorResponse(errCode, message))
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/plastinin/docrecognizer/internal/adapter/http/dto"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"go.uber.org/zap"
+)
+
+// SignedFileStorage хранилище, ссылки на файлы которого обслуживает API
+type SignedFileStorage interface {
+	VerifyURL(method, fileKey, expires, signature string) error
+	Download(ctx context.Context, fileKey string) (io.ReadCloser, error)
+	Put(ctx context.Context, fileKey string, contentType string, reader io.Reader, size int64) error
+}
+
+// FileHandler обработчик подписанных ссылок локального хранилища
+type FileHandler struct {
+	storage    SignedFileStorage
+	pathPrefix string
+	maxSize    int64
+	logger     *zap.Logger
+}
+
+// NewFileHandler создаёт новый FileHandler
+func NewFileHandler(storage SignedFileStorage, pathPrefix string, maxSize int64, logger *zap.Logger) *FileHandler {
+	return &FileHandler{
+		storage:    storage,
+		pathPrefix: pathPrefix,
+		maxSize:    maxSize,
+		logger:     logger,
+	}
+}
+
+// Download отдаёт файл по подписанной ссылке
+// GET /files/{key}?expires=...&signature=...
+func (h *FileHandler) Download(w http.ResponseWriter, r *http.Request) {
+	fileKey, ok := h.verify(w, r)
+	if !ok {
+		return
+	}
+
+	reader, err := h.storage.Download(r.Context(), fileKey)
+	if err != nil {
+		if errors.Is(err, domain.ErrFileNotFound) {
+			h.respondError(w, http.StatusNotFound, "file_not_found", "File not found")
+			return
+		}
+		h.logger.Error("Failed to read file", zap.String("file_key", fileKey), zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "internal_error", "Failed to read file")
+		return
+	}
+	defer reader.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(fileKey))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(fileKey)}))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, reader); err != nil {
+		h.logger.Warn("Failed to stream file", zap.Error(err))
+	}
+}
+
+// Upload принимает файл по подписанной ссылке, выданной POST /api/v1/uploads
+// PUT /files/{key}?expires=...&signature=...
+func (h *FileHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	fileKey, ok := h.verify(w, r)
+	if !ok {
+		return
+	}
+
+	if r.ContentLength > h.maxSize {
+		h.respondError(w, http.StatusRequestEntityTooLarge, "file_too_large", "File is too large")
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, h.maxSize)
+
+	if err := h.storage.Put(r.Context(), fileKey, r.Header.Get("Content-Type"), body, r.ContentLength); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.respondError(w, http.StatusRequestEntityTooLarge, "file_too_large", "File is too large")
+			return
+		}
+		h.logger.Error("Failed to save file", zap.String("file_key", fileKey), zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "internal_error", "Failed to save file")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify проверяет подпись ссылки и возвращает ключ файла;
+// при ошибке отправляет ответ и возвращает false
+func (h *FileHandler) verify(w http.ResponseWriter, r *http.Request) (string, bool) {
+	fileKey := strings.TrimPrefix(r.URL.Path, h.pathPrefix)
+	query := r.URL.Query()
+
+	err := h.storage.VerifyURL(r.Method, fileKey, query.Get("expires"), query.Get("signature"))
+	switch {
+	case err == nil:
+		return fileKey, true
+	case errors.Is(err, domain.ErrSignedURLExpired):
+		h.respondError(w, http.StatusForbidden, "url_expired", "Signed URL has expired")
+	default:
+		h.respondError(w, http.StatusForbidden, "invalid_signature", "Invalid signature")
+	}
+	return "", false
+}
+
+// respondError отправляет ответ с ошибкой
+func (h *FileHandler) respondError(w http.ResponseWriter, status int, errCode string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(dto.NewErrorResponse(errCode, message)); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
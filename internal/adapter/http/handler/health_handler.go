@@ -1,27 +1,136 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Статусы health check
+const (
+	healthStatusOK          = "ok"
+	healthStatusDegraded    = "degraded"
+	healthStatusUnavailable = "unavailable"
 )
 
+// HealthCheck проверка доступности зависимости
+type HealthCheck struct {
+	Name string
+	// Критичная зависимость: при её недоступности сервис не готов принимать запросы
+	Critical bool
+	Check    func(ctx context.Context) error
+}
+
 // HealthHandler обработчик health check запросов
-type HealthHandler struct{}
+type HealthHandler struct {
+	checks  []HealthCheck
+	timeout time.Duration
+	logger  *zap.Logger
+}
 
 // NewHealthHandler создаёт новый HealthHandler
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+func NewHealthHandler(timeout time.Duration, logger *zap.Logger, checks ...HealthCheck) *HealthHandler {
+	return &HealthHandler{
+		checks:  checks,
+		timeout: timeout,
+		logger:  logger,
+	}
 }
 
 // HealthResponse ответ health check
 type HealthResponse struct {
-	Status string `json:"status"`
+	Status     string                       `json:"status"`
+	Components map[string]ComponentResponse `json:"components,omitempty"`
+}
+
+// ComponentResponse состояние отдельной зависимости.
+// Текст ошибки только логируется: он может раскрыть адреса и настройки зависимостей
+type ComponentResponse struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMs int64  `json:"latency_ms"`
 }
 
 // Check проверяет состояние сервиса
 // GET /health
 func (h *HealthHandler) Check(w http.ResponseWriter, r *http.Request) {
+	h.Live(w, r)
+}
+
+// Live проверяет, что процесс жив (зависимости не проверяются)
+// GET /health/live
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, http.StatusOK, HealthResponse{Status: healthStatusOK})
+}
+
+// Ready проверяет доступность зависимостей
+// GET /health/ready
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	components := make(map[string]ComponentResponse, len(h.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	// Проверяем зависимости параллельно
+	for _, check := range h.checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := check.Check(ctx)
+
+			component := ComponentResponse{
+				Status:    healthStatusOK,
+				Critical:  check.Critical,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				component.Status = healthStatusUnavailable
+				h.logger.Warn("Health check failed",
+					zap.String("component", check.Name),
+					zap.Bool("critical", check.Critical),
+					zap.Error(err),
+				)
+			}
+
+			mu.Lock()
+			components[check.Name] = component
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	status := healthStatusOK
+	code := http.StatusOK
+	for _, component := range components {
+		if component.Status == healthStatusOK {
+			continue
+		}
+		if component.Critical {
+			status = healthStatusUnavailable
+			code = http.StatusServiceUnavailable
+			break
+		}
+		status = healthStatusDegraded
+	}
+
+	h.respond(w, code, HealthResponse{
+		Status:     status,
+		Components: components,
+	})
+}
+
+// respond отправляет JSON ответ
+func (h *HealthHandler) respond(w http.ResponseWriter, status int, data HealthResponse) {
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(HealthResponse{Status: "ok"})
-}
\ No newline at end of file
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
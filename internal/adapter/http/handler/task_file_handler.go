@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"go.uber.org/zap"
+)
+
+// GetFile отдаёт исходный файл задачи
+// GET /api/v1/tasks/{id}/file?mode=redirect|proxy
+// redirect (по умолчанию) — редирект на presigned URL хранилища,
+// proxy — файл передаётся через API (если хранилище недоступно клиенту
+// или файлы в нём зашифрованы)
+func (h *TaskHandler) GetFile(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseTaskID(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.URL.Query().Get("mode") {
+	case "", "redirect":
+		url, err := h.taskUC.FileURL(r.Context(), id)
+		if errors.Is(err, domain.ErrDirectURLUnavailable) {
+			// Файлы зашифрованы — отдаём через API
+			h.proxyFile(w, r, id)
+			return
+		}
+		if err != nil {
+			h.handleFileError(w, id, err)
+			return
+		}
+		http.Redirect(w, r, url, http.StatusFound)
+
+	case "proxy":
+		h.proxyFile(w, r, id)
+
+	default:
+		h.respondError(w, http.StatusBadRequest, "invalid_mode", "Mode must be redirect or proxy")
+	}
+}
+
+// proxyFile передаёт исходный файл задачи через API
+func (h *TaskHandler) proxyFile(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	task, reader, err := h.taskUC.OpenFile(r.Context(), id)
+	if err != nil {
+		h.handleFileError(w, id, err)
+		return
+	}
+	defer reader.Close()
+
+	h.streamFile(w, reader, task.ContentType, "attachment", task.FileName)
+}
+
+// GetPage отдаёт изображение страницы, отправленное в модель
+// GET /api/v1/tasks/{id}/pages/{page}.png
+func (h *TaskHandler) GetPage(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.parseTaskID(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(chi.URLParam(r, "page"))
+	if err != nil || page < 1 {
+		h.respondError(w, http.StatusBadRequest, "invalid_page", "Page must be a positive number")
+		return
+	}
+
+	reader, contentType, err := h.taskUC.OpenPage(r.Context(), id, page)
+	if err != nil {
+		h.handleFileError(w, id, err)
+		return
+	}
+	defer reader.Close()
+
+	h.streamFile(w, reader, contentType, "inline", "page-"+strconv.Itoa(page)+".png")
+}
+
+// parseTaskID разбирает ID задачи из URL; при ошибке отправляет ответ и возвращает false
+func (h *TaskHandler) parseTaskID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid_id", "Invalid task ID format")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// streamFile передаёт файл клиенту. Документы содержат персональные данные,
+// поэтому кэширование запрещено
+func (h *TaskHandler) streamFile(w http.ResponseWriter, reader io.Reader, contentType, disposition, fileName string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": fileName}))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, reader); err != nil {
+		h.logger.Warn("Failed to stream file", zap.Error(err))
+	}
+}
+
+// handleFileError отправляет ответ для ошибок доступа к файлам задачи
+func (h *TaskHandler) handleFileError(w http.ResponseWriter, id uuid.UUID, err error) {
+	switch {
+	case errors.Is(err, domain.ErrTaskNotFound):
+		h.respondError(w, http.StatusNotFound, "not_found", "Task not found")
+	case errors.Is(err, domain.ErrTaskFileDeleted):
+		h.respondError(w, http.StatusGone, "file_deleted", "Task file has been deleted by retention policy")
+	case errors.Is(err, domain.ErrFileNotFound):
+		h.respondError(w, http.StatusNotFound, "file_not_found", "File is not available")
+	default:
+		h.logger.Error("Failed to get task file", zap.String("task_id", id.String()), zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "internal_error", "Failed to get task file")
+	}
+}
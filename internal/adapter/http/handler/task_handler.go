@@ -1,10 +1,19 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"path"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
@@ -36,86 +45,389 @@ func NewTaskHandler(taskUC *usecase.TaskUseCase, logger *zap.Logger) *TaskHandle
 // POST /api/v1/tasks
 // Content-Type: multipart/form-data
 // - file: файл документа
-// - schema: JSON массив полей для извлечения
+// - schema: JSON массив полей для извлечения; таблица объявляется как "items[description, quantity, amount=total_amount]"
+// - template, batch_id: необязательные шаблон и пакет загрузки
+// - tenant: необязательный арендатор; по нему применяются политики хранения
+// - dedupe: off (по умолчанию), reuse или link — обработка повторной загрузки того же файла
+// - cache_bypass: true — распознать заново, не используя кэш результатов
+// - render: JSON параметры рендеринга PDF {"dpi": 200, "pages": "1-3,last", "max_width": 2000, "max_height": 2000}
+// - input_mode: image, text или hybrid — отправлять в модель изображения страниц PDF, его текстовый слой или и то, и другое
+// - password: пароль зашифрованного PDF (хранится зашифрованным, не логируется)
+// - classify: true — определить тип документа среди шаблонов и извлечь поля по его схеме (schema тогда необязательна)
+// - split: blank или classify — разделить файл на документы (дочерние задачи) по пустым страницам или смене типа страницы
+// Content-Type: application/json
+// - те же поля, файл передаётся в data (base64) вместе с file_name и content_type
 func (h *TaskHandler) Create(w http.ResponseWriter, r *http.Request) {
-	// Ограничиваем размер загрузки
-	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	var input usecase.CreateTaskInput
+	var ok bool
+	if isJSONRequest(r) {
+		input, ok = h.parseJSONCreate(w, r)
+	} else {
+		input, ok = h.parseMultipartCreate(w, r)
+	}
+	if !ok {
+		return
+	}
+	if closer, isCloser := input.FileReader.(io.Closer); isCloser {
+		defer closer.Close()
+	}
 
-	// Парсим multipart форму
-	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
-		h.logger.Warn("Failed to parse multipart form", zap.Error(err))
-		h.respondError(w, http.StatusBadRequest, "invalid_request", "Failed to parse form data")
+	output, err := h.taskUC.Create(r.Context(), input)
+	if err != nil {
+		h.handleCreateError(w, err)
 		return
 	}
 
-	// Получаем файл
-	file, header, err := r.FormFile("file")
+	// Для переиспользованной задачи ничего не создаётся
+	status := http.StatusCreated
+	if output.Deduplicated {
+		status = http.StatusOK
+	}
+
+	h.respondJSON(w, status, dto.TaskFromDomain(output.Task))
+}
+
+// parseMultipartCreate разбирает multipart запрос на создание задачи.
+// Форма читается потоком: файл передаётся в хранилище по мере чтения тела запроса,
+// без буферизации в памяти или во временных файлах. Поля формы могут идти
+// как до, так и после файла
+func (h *TaskHandler) parseMultipartCreate(w http.ResponseWriter, r *http.Request) (input usecase.CreateTaskInput, ok bool) {
+	// Ограничиваем размер запроса: файл и поля формы
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize+maxJSONRequestSize)
+
+	reader, err := r.MultipartReader()
 	if err != nil {
-		h.logger.Warn("Failed to get file from form", zap.Error(err))
+		h.logger.Warn("Failed to parse multipart form", zap.Error(err))
+		h.respondError(w, http.StatusBadRequest, "invalid_request", "Failed to parse form data")
+		return usecase.CreateTaskInput{}, false
+	}
+
+	var file *usecase.UploadedFile
+	defer func() {
+		// Файл уже в хранилище, но задача не будет создана
+		if !ok && file != nil {
+			h.taskUC.DiscardFile(r.Context(), file)
+		}
+	}()
+
+	var fileName, contentType string
+	fields := make(map[string]string)
+	fieldsSize := 0
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			h.respondFormError(w, err)
+			return usecase.CreateTaskInput{}, false
+		}
+
+		if part.FormName() != "file" {
+			value, err := io.ReadAll(io.LimitReader(part, int64(maxJSONRequestSize-fieldsSize+1)))
+			if err != nil {
+				h.respondFormError(w, err)
+				return usecase.CreateTaskInput{}, false
+			}
+			fieldsSize += len(value)
+			if fieldsSize > maxJSONRequestSize {
+				h.respondError(w, http.StatusRequestEntityTooLarge, "form_too_large", "Form fields exceed maximum size")
+				return usecase.CreateTaskInput{}, false
+			}
+			fields[part.FormName()] = string(value)
+			continue
+		}
+
+		if file != nil {
+			h.respondError(w, http.StatusBadRequest, "multiple_files", "Only one file is allowed")
+			return usecase.CreateTaskInput{}, false
+		}
+
+		// Тип файла проверяем до загрузки, чтобы не передавать в хранилище лишнее
+		fileName = path.Base(part.FileName())
+		contentType, ok = h.resolveContentType(w, fileName, part.Header.Get("Content-Type"))
+		if !ok {
+			return usecase.CreateTaskInput{}, false
+		}
+
+		limited := &uploadLimitReader{reader: part, remaining: maxUploadSize}
+		file, err = h.taskUC.UploadFile(r.Context(), fileName, contentType, limited, -1)
+		if limited.exceeded {
+			err = domain.ErrFileTooLarge
+		}
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				err = domain.ErrFileTooLarge
+			}
+			h.handleCreateError(w, err)
+			return usecase.CreateTaskInput{}, false
+		}
+	}
+
+	if file == nil {
 		h.respondError(w, http.StatusBadRequest, "file_required", "File is required")
-		return
+		return usecase.CreateTaskInput{}, false
 	}
-	defer file.Close()
 
-	// Получаем schema
-	schemaJSON := r.FormValue("schema")
-	if schemaJSON == "" {
+	classify := false
+	if v := fields["classify"]; v != "" {
+		classify, err = strconv.ParseBool(v)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid_classify", "classify must be a boolean")
+			return usecase.CreateTaskInput{}, false
+		}
+	}
+
+	// Получаем schema; при классификации она необязательна
+	schemaJSON := fields["schema"]
+	if schemaJSON == "" && !classify {
 		h.respondError(w, http.StatusBadRequest, "schema_required", "Schema is required")
-		return
+		return usecase.CreateTaskInput{}, false
 	}
 
 	var schema []string
-	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid_schema", "Schema must be a JSON array of strings")
+	if schemaJSON != "" {
+		if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid_schema", "Schema must be a JSON array of strings")
+			return usecase.CreateTaskInput{}, false
+		}
+	}
+
+	cacheBypass := false
+	if v := fields["cache_bypass"]; v != "" {
+		cacheBypass, err = strconv.ParseBool(v)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid_cache_bypass", "cache_bypass must be a boolean")
+			return usecase.CreateTaskInput{}, false
+		}
+	}
+
+	var render domain.RenderOptions
+	if v := fields["render"]; v != "" {
+		if err := json.Unmarshal([]byte(v), &render); err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid_render_options", "Render must be a JSON object")
+			return usecase.CreateTaskInput{}, false
+		}
+	}
+
+	input, ok = h.buildCreateInput(w, createParams{
+		FileName:    fileName,
+		ContentType: contentType,
+		Schema:      schema,
+		Template:    fields["template"],
+		Tenant:      fields["tenant"],
+		BatchID:     fields["batch_id"],
+		Dedupe:      fields["dedupe"],
+		CacheBypass: cacheBypass,
+		Render:      render,
+		InputMode:   fields["input_mode"],
+		Password:    fields["password"],
+		Classify:    classify,
+		Split:       fields["split"],
+	})
+	if !ok {
+		return usecase.CreateTaskInput{}, false
+	}
+
+	input.FileSize = file.Size
+	input.File = file
+	return input, true
+}
+
+// respondFormError отправляет ответ для ошибок чтения multipart формы
+func (h *TaskHandler) respondFormError(w http.ResponseWriter, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		h.respondError(w, http.StatusRequestEntityTooLarge, "file_too_large", "File exceeds maximum upload size")
 		return
 	}
+	h.logger.Warn("Failed to read multipart form", zap.Error(err))
+	h.respondError(w, http.StatusBadRequest, "invalid_request", "Failed to parse form data")
+}
+
+// uploadLimitReader ограничивает размер файла при потоковой загрузке.
+// Флаг exceeded нужен, потому что клиент хранилища может не сохранить
+// исходную ошибку чтения
+type uploadLimitReader struct {
+	reader    io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (r *uploadLimitReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		r.exceeded = true
+		return 0, domain.ErrFileTooLarge
+	}
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
+	}
+	n, err := r.reader.Read(p)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		r.exceeded = true
+		return 0, domain.ErrFileTooLarge
+	}
+	return n, err
+}
+
+// parseJSONCreate разбирает JSON запрос на создание задачи с файлом в base64
+func (h *TaskHandler) parseJSONCreate(w http.ResponseWriter, r *http.Request) (usecase.CreateTaskInput, bool) {
+	// base64 увеличивает размер на треть, плюс запас на остальные поля
+	r.Body = http.MaxBytesReader(w, r.Body, int64(base64.StdEncoding.EncodedLen(maxUploadSize)+maxJSONRequestSize))
+
+	var req dto.CreateTaskJSONRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.respondError(w, http.StatusRequestEntityTooLarge, "file_too_large", "File exceeds maximum upload size")
+			return usecase.CreateTaskInput{}, false
+		}
+		h.respondError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
+		return usecase.CreateTaskInput{}, false
+	}
+
+	if req.Data == "" {
+		h.respondError(w, http.StatusBadRequest, "file_required", "File data is required")
+		return usecase.CreateTaskInput{}, false
+	}
+	if req.FileName == "" {
+		h.respondError(w, http.StatusBadRequest, "file_name_required", "File name is required")
+		return usecase.CreateTaskInput{}, false
+	}
+
+	data, err := base64.StdEncoding.DecodeString(req.Data)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid_data", "Data must be base64 encoded")
+		return usecase.CreateTaskInput{}, false
+	}
+	if len(data) > maxUploadSize {
+		h.respondError(w, http.StatusRequestEntityTooLarge, "file_too_large", "File exceeds maximum upload size")
+		return usecase.CreateTaskInput{}, false
+	}
+
+	input, ok := h.buildCreateInput(w, createParams{
+		FileName:    path.Base(req.FileName),
+		ContentType: req.ContentType,
+		Schema:      req.Schema,
+		Template:    req.Template,
+		Tenant:      req.Tenant,
+		BatchID:     req.BatchID,
+		Dedupe:      req.Dedupe,
+		CacheBypass: req.CacheBypass,
+		Render:      renderOrZero(req.Render),
+		InputMode:   req.InputMode,
+		Password:    req.Password,
+		Classify:    req.Classify,
+		Split:       req.Split,
+	})
+	if !ok {
+		return usecase.CreateTaskInput{}, false
+	}
+
+	input.FileSize = int64(len(data))
+	input.FileReader = bytes.NewReader(data)
+	return input, true
+}
 
-	if len(schema) == 0 {
+// createParams параметры создания задачи, общие для multipart и JSON запросов
+type createParams struct {
+	FileName    string
+	ContentType string
+	Schema      []string
+	Template    string
+	Tenant      string
+	BatchID     string
+	Dedupe      string
+	CacheBypass bool
+	Render      domain.RenderOptions
+	InputMode   string
+	Password    string
+	Classify    bool
+	Split       string
+}
+
+// buildCreateInput валидирует параметры создания задачи; при ошибке отправляет ответ и возвращает false
+func (h *TaskHandler) buildCreateInput(w http.ResponseWriter, p createParams) (usecase.CreateTaskInput, bool) {
+	if len(p.Schema) == 0 && !p.Classify {
 		h.respondError(w, http.StatusBadRequest, "empty_schema", "Schema cannot be empty")
-		return
+		return usecase.CreateTaskInput{}, false
+	}
+
+	batchID, ok := h.parseBatchID(w, p.BatchID)
+	if !ok {
+		return usecase.CreateTaskInput{}, false
+	}
+
+	dedupe, err := domain.ParseDedupeMode(p.Dedupe)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid_dedupe", "Dedupe must be one of: off, reuse, link")
+		return usecase.CreateTaskInput{}, false
+	}
+
+	if !h.validateRender(w, p.Render) {
+		return usecase.CreateTaskInput{}, false
 	}
 
-	// Определяем content type
-	contentType := header.Header.Get("Content-Type")
+	inputMode, ok := h.parseInputMode(w, p.InputMode)
+	if !ok {
+		return usecase.CreateTaskInput{}, false
+	}
+
+	split, ok := h.parseSplitMode(w, p.Split)
+	if !ok {
+		return usecase.CreateTaskInput{}, false
+	}
+
+	contentType, ok := h.resolveContentType(w, p.FileName, p.ContentType)
+	if !ok {
+		return usecase.CreateTaskInput{}, false
+	}
+
+	return usecase.CreateTaskInput{
+		FileName:    p.FileName,
+		ContentType: contentType,
+		Schema:      p.Schema,
+		Template:    p.Template,
+		Tenant:      p.Tenant,
+		BatchID:     batchID,
+		Dedupe:      dedupe,
+		CacheBypass: p.CacheBypass,
+		Render:      p.Render,
+		InputMode:   inputMode,
+		Password:    p.Password,
+		Classify:    p.Classify,
+		Split:       split,
+	}, true
+}
+
+// resolveContentType определяет и валидирует тип файла; при ошибке отправляет ответ и возвращает false
+func (h *TaskHandler) resolveContentType(w http.ResponseWriter, fileName, contentType string) (string, bool) {
 	if contentType == "" {
 		// Пытаемся определить по расширению
-		ct, err := domain.ContentTypeFromFileName(header.Filename)
+		ct, err := domain.ContentTypeFromFileName(fileName)
 		if err != nil {
 			h.respondError(w, http.StatusBadRequest, "invalid_file_type", "Unsupported file type")
-			return
+			return "", false
 		}
 		contentType = ct
 	}
 
 	// Валидируем тип файла
 	if err := domain.ValidateContentType(contentType); err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid_file_type", "Unsupported file type. Supported: PNG, JPEG, WEBP, TIFF, PDF")
-		return
-	}
-
-	// Создаём задачу
-	input := usecase.CreateTaskInput{
-		FileName:    header.Filename,
-		ContentType: contentType,
-		FileSize:    header.Size,
-		FileReader:  file,
-		Schema:      schema,
+		h.respondError(w, http.StatusBadRequest, "invalid_file_type", "Unsupported file type. Supported: PNG, JPEG, WEBP, TIFF, PDF, DOCX, XLSX, ODT, HTML, TXT, EML, XPS, EPUB")
+		return "", false
 	}
 
-	task, err := h.taskUC.Create(r.Context(), input)
-	if err != nil {
-		h.logger.Error("Failed to create task", zap.Error(err))
-		
-		if errors.Is(err, domain.ErrUnsupportedFileType) {
-			h.respondError(w, http.StatusBadRequest, "invalid_file_type", err.Error())
-			return
-		}
-		
-		h.respondError(w, http.StatusInternalServerError, "internal_error", "Failed to create task")
-		return
-	}
+	return contentType, true
+}
 
-	h.respondJSON(w, http.StatusCreated, dto.TaskFromDomain(task))
+// isJSONRequest проверяет, что тело запроса передано в JSON
+func isJSONRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
 }
 
 // GetByID возвращает задачу по ID
@@ -144,23 +456,45 @@ func (h *TaskHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 // List возвращает список задач
 // GET /api/v1/tasks?page=1&page_size=20&status=pending
+// Фильтры: created_from, created_to, completed_from, completed_to (RFC 3339 или YYYY-MM-DD),
+// file_name, content_type, template, tenant, batch_id, parent_id, q (поиск по результату), result.<поле>=<значение>
+// Сортировка: sort=created_at|updated_at|completed_at|file_name|status, order=asc|desc
+// Keyset пагинация: cursor (пустой для первой страницы, далее next_cursor из ответа);
+// общее количество для keyset считается только с include_total=true
 func (h *TaskHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
 	// Парсим параметры пагинации
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
 	pagination := domain.NewPagination(page, pageSize)
 
-	// Парсим фильтры
-	filter := domain.TaskFilter{}
-	if statusStr := r.URL.Query().Get("status"); statusStr != "" {
-		status := domain.TaskStatus(statusStr)
-		if status.IsValid() {
-			filter.Status = &status
+	if query.Has("cursor") {
+		var after *domain.Cursor
+		if cursorStr := query.Get("cursor"); cursorStr != "" {
+			cursor, err := domain.DecodeCursor(cursorStr)
+			if err != nil {
+				h.respondError(w, http.StatusBadRequest, "invalid_cursor", "Invalid cursor")
+				return
+			}
+			after = cursor
 		}
+		pagination = domain.NewCursorPagination(after, pageSize, query.Get("include_total") == "true")
+	}
+
+	// Парсим фильтры
+	filter, err := parseTaskFilter(query)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid_filter", err.Error())
+		return
 	}
 
 	result, err := h.taskUC.List(r.Context(), filter, pagination)
 	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCursor) {
+			h.respondError(w, http.StatusBadRequest, "invalid_cursor", "Cursor does not match sort order")
+			return
+		}
 		h.logger.Error("Failed to list tasks", zap.Error(err))
 		h.respondError(w, http.StatusInternalServerError, "internal_error", "Failed to list tasks")
 		return
@@ -169,6 +503,90 @@ func (h *TaskHandler) List(w http.ResponseWriter, r *http.Request) {
 	h.respondJSON(w, http.StatusOK, dto.TaskListFromDomain(result))
 }
 
+// parseTaskFilter разбирает фильтры и сортировку списка задач из query параметров
+func parseTaskFilter(query url.Values) (domain.TaskFilter, error) {
+	filter := domain.TaskFilter{
+		FileName:    query.Get("file_name"),
+		ContentType: query.Get("content_type"),
+		Template:    query.Get("template"),
+		Tenant:      query.Get("tenant"),
+		FileHash:    strings.ToLower(query.Get("file_hash")),
+		Search:      query.Get("q"),
+	}
+
+	if statusStr := query.Get("status"); statusStr != "" {
+		status := domain.TaskStatus(statusStr)
+		if status.IsValid() {
+			filter.Status = &status
+		}
+	}
+
+	dates := []struct {
+		param string
+		dst   **time.Time
+	}{
+		{"created_from", &filter.CreatedFrom},
+		{"created_to", &filter.CreatedTo},
+		{"completed_from", &filter.CompletedFrom},
+		{"completed_to", &filter.CompletedTo},
+	}
+	for _, d := range dates {
+		value := query.Get(d.param)
+		if value == "" {
+			continue
+		}
+		t, err := parseFilterTime(value)
+		if err != nil {
+			return domain.TaskFilter{}, fmt.Errorf("%s must be RFC 3339 timestamp or YYYY-MM-DD date", d.param)
+		}
+		*d.dst = &t
+	}
+
+	if batchIDStr := query.Get("batch_id"); batchIDStr != "" {
+		id, err := uuid.Parse(batchIDStr)
+		if err != nil {
+			return domain.TaskFilter{}, errors.New("batch_id must be UUID")
+		}
+		filter.BatchID = &id
+	}
+
+	if parentIDStr := query.Get("parent_id"); parentIDStr != "" {
+		id, err := uuid.Parse(parentIDStr)
+		if err != nil {
+			return domain.TaskFilter{}, errors.New("parent_id must be UUID")
+		}
+		filter.ParentID = &id
+	}
+
+	// Фильтры по полям результата: result.invoice_number=INV-1
+	for key, values := range query {
+		field, ok := strings.CutPrefix(key, "result.")
+		if !ok || field == "" || len(values) == 0 {
+			continue
+		}
+		if filter.ResultFields == nil {
+			filter.ResultFields = make(map[string]string)
+		}
+		filter.ResultFields[field] = values[0]
+	}
+
+	sort, err := domain.NewTaskSort(query.Get("sort"), query.Get("order"))
+	if err != nil {
+		return domain.TaskFilter{}, errors.New("sort must be one of created_at, updated_at, completed_at, file_name, status; order must be asc or desc")
+	}
+	filter.Sort = sort
+
+	return filter, nil
+}
+
+// parseFilterTime разбирает время в формате RFC 3339 или дату YYYY-MM-DD
+func parseFilterTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
 // Delete удаляет задачу
 // DELETE /api/v1/tasks/{id}
 func (h *TaskHandler) Delete(w http.ResponseWriter, r *http.Request) {
@@ -205,4 +623,4 @@ func (h *TaskHandler) respondJSON(w http.ResponseWriter, status int, data any) {
 // respondError отправляет ответ с ошибкой
 func (h *TaskHandler) respondError(w http.ResponseWriter, status int, errCode string, message string) {
 	h.respondJSON(w, status, dto.NewErrorResponse(errCode, message))
-}
\ No newline at end of file
+}
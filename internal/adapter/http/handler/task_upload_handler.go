@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/plastinin/docrecognizer/internal/adapter/http/dto"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/internal/usecase"
+	"go.uber.org/zap"
+)
+
+// maxJSONRequestSize ограничение размера JSON запросов без файла
+const maxJSONRequestSize = 1 << 20 // 1 MB
+
+// CreateFromURL создаёт задачу, документ для которой воркер скачает по URL
+// POST /api/v1/tasks/from-url
+// {"url": "https://...", "schema": ["field"], "file_name": "...", "template": "...", "tenant": "...", "batch_id": "...", "cache_bypass": false, "render": {...}, "input_mode": "hybrid", "password": "...", "classify": false, "split": "blank"}
+func (h *TaskHandler) CreateFromURL(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateTaskFromURLRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if len(req.Schema) == 0 && !req.Classify {
+		h.respondError(w, http.StatusBadRequest, "empty_schema", "Schema cannot be empty")
+		return
+	}
+
+	batchID, ok := h.parseBatchID(w, req.BatchID)
+	if !ok {
+		return
+	}
+
+	render := renderOrZero(req.Render)
+	if !h.validateRender(w, render) {
+		return
+	}
+
+	inputMode, ok := h.parseInputMode(w, req.InputMode)
+	if !ok {
+		return
+	}
+
+	split, ok := h.parseSplitMode(w, req.Split)
+	if !ok {
+		return
+	}
+
+	task, err := h.taskUC.CreateFromURL(r.Context(), usecase.CreateTaskFromURLInput{
+		SourceURL:   req.URL,
+		FileName:    req.FileName,
+		Schema:      req.Schema,
+		Template:    req.Template,
+		Tenant:      req.Tenant,
+		BatchID:     batchID,
+		CacheBypass: req.CacheBypass,
+		Render:      render,
+		InputMode:   inputMode,
+		Password:    req.Password,
+		Classify:    req.Classify,
+		Split:       split,
+	})
+	if err != nil {
+		h.handleCreateError(w, err)
+		return
+	}
+
+	// Документ ещё не скачан: задача принята к обработке
+	h.respondJSON(w, http.StatusAccepted, dto.TaskFromDomain(task))
+}
+
+// CreateUpload выдаёт presigned URL для загрузки файла напрямую в хранилище
+// POST /api/v1/uploads
+// {"file_name": "invoice.pdf"}
+func (h *TaskHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateUploadRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.FileName == "" {
+		h.respondError(w, http.StatusBadRequest, "file_name_required", "File name is required")
+		return
+	}
+
+	upload, err := h.taskUC.CreateUpload(r.Context(), req.FileName)
+	if err != nil {
+		h.handleCreateError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, dto.UploadFromDomain(upload))
+}
+
+// CreateFromUpload создаёт задачу по файлу, загруженному по presigned URL
+// POST /api/v1/tasks/from-upload
+// {"file_key": "uploads/...", "schema": ["field"], "template": "...", "tenant": "...", "batch_id": "...", "cache_bypass": false, "render": {...}, "input_mode": "hybrid", "password": "...", "classify": false, "split": "blank"}
+func (h *TaskHandler) CreateFromUpload(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateTaskFromUploadRequest
+	if !h.decodeJSON(w, r, &req) {
+		return
+	}
+
+	if len(req.Schema) == 0 && !req.Classify {
+		h.respondError(w, http.StatusBadRequest, "empty_schema", "Schema cannot be empty")
+		return
+	}
+
+	batchID, ok := h.parseBatchID(w, req.BatchID)
+	if !ok {
+		return
+	}
+
+	render := renderOrZero(req.Render)
+	if !h.validateRender(w, render) {
+		return
+	}
+
+	inputMode, ok := h.parseInputMode(w, req.InputMode)
+	if !ok {
+		return
+	}
+
+	split, ok := h.parseSplitMode(w, req.Split)
+	if !ok {
+		return
+	}
+
+	task, err := h.taskUC.CreateFromUpload(r.Context(), usecase.CreateTaskFromUploadInput{
+		FileKey:     req.FileKey,
+		Schema:      req.Schema,
+		Template:    req.Template,
+		Tenant:      req.Tenant,
+		BatchID:     batchID,
+		CacheBypass: req.CacheBypass,
+		Render:      render,
+		InputMode:   inputMode,
+		Password:    req.Password,
+		Classify:    req.Classify,
+		Split:       split,
+	})
+	if err != nil {
+		h.handleCreateError(w, err)
+		return
+	}
+
+	h.respondJSON(w, http.StatusCreated, dto.TaskFromDomain(task))
+}
+
+// decodeJSON читает JSON тело запроса; при ошибке отправляет ответ и возвращает false
+func (h *TaskHandler) decodeJSON(w http.ResponseWriter, r *http.Request, dst any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxJSONRequestSize)
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid_request", "Invalid JSON body")
+		return false
+	}
+	return true
+}
+
+// parseBatchID разбирает необязательный ID пакета; при ошибке отправляет ответ и возвращает false
+func (h *TaskHandler) parseBatchID(w http.ResponseWriter, value string) (*uuid.UUID, bool) {
+	if value == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid_batch_id", "Invalid batch ID format")
+		return nil, false
+	}
+	return &id, true
+}
+
+// validateRender проверяет параметры рендеринга PDF; при ошибке отправляет ответ и возвращает false
+func (h *TaskHandler) validateRender(w http.ResponseWriter, render domain.RenderOptions) bool {
+	if err := render.Validate(); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid_render_options", err.Error())
+		return false
+	}
+	return true
+}
+
+// parseInputMode разбирает необязательный режим входных данных модели; при ошибке отправляет ответ и возвращает false
+func (h *TaskHandler) parseInputMode(w http.ResponseWriter, value string) (domain.InputMode, bool) {
+	mode, err := domain.ParseInputMode(value)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid_input_mode", "Input mode must be one of: image, text, hybrid")
+		return "", false
+	}
+	return mode, true
+}
+
+// parseSplitMode разбирает необязательный режим разделения файла; при ошибке отправляет ответ и возвращает false
+func (h *TaskHandler) parseSplitMode(w http.ResponseWriter, value string) (domain.SplitMode, bool) {
+	mode, err := domain.ParseSplitMode(value)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid_split", "Split must be one of: blank, classify")
+		return "", false
+	}
+	return mode, true
+}
+
+// renderOrZero возвращает параметры рендеринга из запроса или пустые, если они не заданы
+func renderOrZero(render *domain.RenderOptions) domain.RenderOptions {
+	if render == nil {
+		return domain.RenderOptions{}
+	}
+	return *render
+}
+
+// handleCreateError отправляет ответ для ошибок создания задачи
+func (h *TaskHandler) handleCreateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidSourceURL):
+		h.respondError(w, http.StatusBadRequest, "invalid_url", "URL must be an absolute http or https URL")
+	case errors.Is(err, domain.ErrDirectURLUnavailable):
+		h.respondError(w, http.StatusNotImplemented, "direct_upload_unavailable", "Direct upload is unavailable while storage encryption is enabled, use POST /api/v1/tasks")
+	case errors.Is(err, domain.ErrInvalidUploadKey):
+		h.respondError(w, http.StatusBadRequest, "invalid_file_key", "File key must be obtained from /api/v1/uploads")
+	case errors.Is(err, domain.ErrFileKeyInUse):
+		h.respondError(w, http.StatusConflict, "file_key_in_use", "A task has already been created for this file key")
+	case errors.Is(err, domain.ErrFileNotFound):
+		h.respondError(w, http.StatusNotFound, "file_not_found", "Uploaded file not found")
+	case errors.Is(err, domain.ErrFileTooLarge):
+		h.respondError(w, http.StatusRequestEntityTooLarge, "file_too_large", err.Error())
+	case errors.Is(err, domain.ErrInvalidSchema):
+		h.respondError(w, http.StatusBadRequest, "invalid_schema", err.Error())
+	case errors.Is(err, domain.ErrInvalidTenant):
+		h.respondError(w, http.StatusBadRequest, "invalid_tenant", "Tenant must be up to 64 latin letters, digits, '.', '_' or '-'")
+	case errors.Is(err, domain.ErrSplitUnsupported):
+		h.respondError(w, http.StatusBadRequest, "split_unsupported", err.Error())
+	case errors.Is(err, domain.ErrPasswordsDisabled):
+		h.respondError(w, http.StatusBadRequest, "password_unsupported", "Document passwords are not enabled on this server")
+	case errors.Is(err, domain.ErrUnsupportedFileType):
+		h.respondError(w, http.StatusBadRequest, "invalid_file_type", "Unsupported file type. Supported: PNG, JPEG, WEBP, TIFF, PDF, DOCX, XLSX, ODT, HTML, TXT, EML, XPS, EPUB")
+	default:
+		h.logger.Error("Failed to create task", zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "internal_error", "Failed to create task")
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/plastinin/docrecognizer/internal/adapter/http/dto"
+)
+
+// NewAdminAuthMiddleware создаёт middleware, пропускающий только запросы
+// с заголовком "Authorization: Bearer <token>". Токен сравнивается за постоянное время
+func NewAdminAuthMiddleware(token string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				_ = json.NewEncoder(w).Encode(dto.NewErrorResponse("unauthorized", "admin token required"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,9 +2,14 @@ package middleware
 
 import (
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/plastinin/docrecognizer/pkg/metrics"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
 	"go.uber.org/zap"
 )
 
@@ -13,7 +18,8 @@ type LoggingMiddleware struct {
 	logger *zap.Logger
 }
 
-// NewLoggingMiddleware создаёт новый LoggingMiddleware
+// NewLoggingMiddleware создаёт новый LoggingMiddleware.
+// Помимо логирования учитывает запросы в метриках Prometheus
 func NewLoggingMiddleware(logger *zap.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -23,14 +29,18 @@ func NewLoggingMiddleware(logger *zap.Logger) func(next http.Handler) http.Handl
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
 			defer func() {
+				duration := time.Since(start)
+				metrics.ObserveHTTPRequest(r.Method, routePattern(r), ww.Status(), duration)
+
 				logger.Info("HTTP request",
 					zap.String("method", r.Method),
 					zap.String("path", r.URL.Path),
-					zap.String("query", r.URL.RawQuery),
+					zap.String("query", redactQuery(r.URL.RawQuery)),
 					zap.Int("status", ww.Status()),
 					zap.Int("bytes", ww.BytesWritten()),
-					zap.Duration("duration", time.Since(start)),
+					zap.Duration("duration", duration),
 					zap.String("request_id", middleware.GetReqID(r.Context())),
+					zap.String("trace_id", tracing.TraceID(r.Context())),
 					zap.String("remote_addr", r.RemoteAddr),
 				)
 			}()
@@ -38,4 +48,38 @@ func NewLoggingMiddleware(logger *zap.Logger) func(next http.Handler) http.Handl
 			next.ServeHTTP(ww, r)
 		})
 	}
-}
\ No newline at end of file
+}
+
+// redactedQueryParams параметры запроса, которые являются учётными данными:
+// подпись ссылки /files/ даёт доступ к файлу до истечения срока
+var redactedQueryParams = []string{"signature", "x-amz-signature", "x-amz-credential"}
+
+// redactQuery скрывает значения учётных данных в строке запроса для лога
+func redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Неразобранная строка может содержать подпись целиком
+		return "[unparsed]"
+	}
+	for name := range query {
+		for _, redacted := range redactedQueryParams {
+			if strings.EqualFold(name, redacted) {
+				query[name] = []string{"REDACTED"}
+			}
+		}
+	}
+	return query.Encode()
+}
+
+// routePattern возвращает шаблон маршрута chi, чтобы не раздувать кардинальность метрик
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return "unmatched"
+}
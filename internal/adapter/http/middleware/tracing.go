@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// NewTracingMiddleware создаёт middleware, открывающий серверный спан на каждый запрос.
+// Входящий контекст трассировки (traceparent) подхватывается из заголовков
+func NewTracingMiddleware() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+r.URL.Path,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("request_id", middleware.GetReqID(r.Context())),
+				),
+			)
+			defer span.End()
+
+			// Отдаём ID трассировки клиенту для поиска в бэкенде трассировок
+			if traceID := tracing.TraceID(ctx); traceID != "" {
+				w.Header().Set("X-Trace-ID", traceID)
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			r = r.WithContext(ctx)
+
+			next.ServeHTTP(ww, r)
+
+			// Маршрут известен только после роутинга
+			route := routePattern(r)
+			span.SetName(r.Method + " " + route)
+			span.SetAttributes(
+				attribute.String("http.route", route),
+				attribute.Int("http.response.status_code", ww.Status()),
+			)
+			if ww.Status() >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(ww.Status()))
+			}
+		})
+	}
+}
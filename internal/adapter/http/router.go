@@ -5,13 +5,18 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/plastinin/docrecognizer/internal/adapter/http/handler"
 	httpmiddleware "github.com/plastinin/docrecognizer/internal/adapter/http/middleware"
+	"github.com/plastinin/docrecognizer/pkg/metrics"
 	"go.uber.org/zap"
 )
 
-// NewRouter создаёт и настраивает HTTP роутер
+// NewRouter создаёт и настраивает HTTP роутер.
+// API администрирования доступен только с токеном adminToken; пустой токен отключает его
 func NewRouter(
 	taskHandler *handler.TaskHandler,
 	healthHandler *handler.HealthHandler,
+	adminHandler *handler.AdminHandler,
+	fileHandler *handler.FileHandler,
+	adminToken string,
 	logger *zap.Logger,
 ) *chi.Mux {
 	r := chi.NewRouter()
@@ -19,23 +24,74 @@ func NewRouter(
 	// Middleware
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(httpmiddleware.NewTracingMiddleware())
 	r.Use(httpmiddleware.NewLoggingMiddleware(logger))
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Compress(5))
 
 	// Health check (вне версионирования API)
 	r.Get("/health", healthHandler.Check)
+	r.Get("/health/live", healthHandler.Live)
+	r.Get("/health/ready", healthHandler.Ready)
+
+	// Метрики Prometheus
+	r.Handle("/metrics", metrics.Handler())
+
+	// Подписанные ссылки локального хранилища (nil для S3)
+	if fileHandler != nil {
+		r.Get("/files/*", fileHandler.Download)
+		r.Put("/files/*", fileHandler.Upload)
+	}
 
 	// API v1
 	r.Route("/api/v1", func(r chi.Router) {
+		// Прямая загрузка файлов в хранилище
+		r.Post("/uploads", taskHandler.CreateUpload)
+
 		// Tasks
 		r.Route("/tasks", func(r chi.Router) {
 			r.Post("/", taskHandler.Create)
+			r.Post("/from-url", taskHandler.CreateFromURL)
+			r.Post("/from-upload", taskHandler.CreateFromUpload)
 			r.Get("/", taskHandler.List)
 			r.Get("/{id}", taskHandler.GetByID)
+			r.Get("/{id}/file", taskHandler.GetFile)
+			r.Get("/{id}/pages/{page}.png", taskHandler.GetPage)
 			r.Delete("/{id}", taskHandler.Delete)
 		})
+
+		// Администрирование очереди
+		if adminToken != "" {
+			r.Route("/admin/queue", func(r chi.Router) {
+				r.Use(httpmiddleware.NewAdminAuthMiddleware(adminToken))
+
+				r.Get("/", adminHandler.QueueInfo)
+				r.Post("/pause", adminHandler.PauseQueue)
+				r.Post("/resume", adminHandler.ResumeQueue)
+				r.Get("/tasks", adminHandler.ListTasks)
+				r.Get("/tasks/{id}", adminHandler.GetTask)
+				r.Post("/tasks/{id}/requeue", adminHandler.RequeueTask)
+				r.Delete("/tasks/{id}", adminHandler.DeleteTask)
+			})
+		}
 	})
 
 	return r
-}
\ No newline at end of file
+}
+
+// NewWorkerRouter создаёт служебный HTTP роутер воркера
+func NewWorkerRouter(healthHandler *handler.HealthHandler) *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(middleware.Recoverer)
+
+	// Health check
+	r.Get("/health", healthHandler.Check)
+	r.Get("/health/live", healthHandler.Live)
+	r.Get("/health/ready", healthHandler.Ready)
+
+	// Метрики Prometheus
+	r.Handle("/metrics", metrics.Handler())
+
+	return r
+}
@@ -0,0 +1,308 @@
+package llm
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// maxConvertedPartSize ограничение размера текстовой части письма или содержимого ODT
+const maxConvertedPartSize = 10 << 20 // 10 MB
+
+// convertToHTML конвертирует форматы, которые MuPDF не открывает сам (ODT, EML), в HTML.
+// Возвращает путь к HTML файлу рядом с исходным или пустую строку, если конвертация не нужна
+func convertToHTML(path string) (string, error) {
+	var convert func(path string) ([]byte, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".odt":
+		convert = convertODT
+	case ".eml":
+		convert = convertEML
+	default:
+		return "", nil
+	}
+
+	content, err := convert(path)
+	if err != nil {
+		return "", err
+	}
+
+	converted := path + ".html"
+	if err := os.WriteFile(converted, content, 0o600); err != nil {
+		return "", fmt.Errorf("failed to write converted document: %w", err)
+	}
+	return converted, nil
+}
+
+// convertODT конвертирует текст документа OpenDocument (content.xml) в HTML:
+// заголовки, абзацы, списки и таблицы
+func convertODT(path string) ([]byte, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ODT: %w", err)
+	}
+	defer archive.Close()
+
+	content, err := archive.Open("content.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ODT content: %w", err)
+	}
+	defer content.Close()
+
+	// Элементы ODT и соответствующие им теги HTML
+	tags := map[string]string{
+		"h":          "h2",
+		"p":          "p",
+		"list":       "ul",
+		"list-item":  "li",
+		"table":      "table border=\"1\"",
+		"table-row":  "tr",
+		"table-cell": "td",
+	}
+
+	var b bytes.Buffer
+	b.WriteString("<html><body>")
+
+	decoder := xml.NewDecoder(io.LimitReader(content, maxConvertedPartSize))
+	skip := 0 // Глубина вложенности в пропускаемые элементы (примечания, аннотации)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ODT content: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skip > 0 || t.Name.Local == "note" || t.Name.Local == "annotation" {
+				skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "tab":
+				b.WriteString("\t")
+			case "line-break":
+				b.WriteString("<br>")
+			case "s":
+				count := 1
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "c" {
+						if n, err := strconv.Atoi(attr.Value); err == nil && n > 0 {
+							count = n
+						}
+					}
+				}
+				b.WriteString(strings.Repeat("&nbsp;", count))
+			default:
+				if tag, ok := tags[t.Name.Local]; ok {
+					b.WriteString("<" + tag + ">")
+				}
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if tag, ok := tags[t.Name.Local]; ok {
+				name, _, _ := strings.Cut(tag, " ")
+				b.WriteString("</" + name + ">")
+			}
+		case xml.CharData:
+			if skip == 0 {
+				b.WriteString(html.EscapeString(string(t)))
+			}
+		}
+	}
+
+	b.WriteString("</body></html>")
+	return b.Bytes(), nil
+}
+
+// convertEML конвертирует письмо в HTML: заголовки, текст письма и список вложений.
+// Из альтернативных частей выбирается HTML, вложения не раскрываются
+func convertEML(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	msg, err := mail.ReadMessage(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email: %w", err)
+	}
+
+	decoder := mime.WordDecoder{CharsetReader: charsetReader}
+
+	var b bytes.Buffer
+	b.WriteString("<html><body><table>")
+	for _, name := range []string{"From", "To", "Cc", "Date", "Subject"} {
+		value := msg.Header.Get(name)
+		if value == "" {
+			continue
+		}
+		if decoded, err := decoder.DecodeHeader(value); err == nil {
+			value = decoded
+		}
+		fmt.Fprintf(&b, "<tr><th align=\"left\">%s:</th><td>%s</td></tr>", name, html.EscapeString(value))
+	}
+	b.WriteString("</table><hr>")
+
+	email := &emailBody{decoder: decoder}
+	if err := email.walk(msg.Header, msg.Body); err != nil {
+		return nil, fmt.Errorf("failed to read email body: %w", err)
+	}
+	b.Write(email.body.Bytes())
+
+	if len(email.attachments) > 0 {
+		b.WriteString("<hr><p>Attachments:</p><ul>")
+		for _, name := range email.attachments {
+			fmt.Fprintf(&b, "<li>%s</li>", html.EscapeString(name))
+		}
+		b.WriteString("</ul>")
+	}
+
+	b.WriteString("</body></html>")
+	return b.Bytes(), nil
+}
+
+// emailHeader заголовки письма (mail.Header) или его части (textproto.MIMEHeader)
+type emailHeader interface {
+	Get(key string) string
+}
+
+// emailBody собирает текст письма при обходе MIME частей
+type emailBody struct {
+	decoder     mime.WordDecoder
+	body        bytes.Buffer
+	attachments []string
+}
+
+// walk обходит MIME часть письма
+func (e *emailBody) walk(header emailHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// Без Content-Type письмо считается простым текстом
+		mediaType, params = "text/plain", nil
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if disposition == "attachment" || (!strings.HasPrefix(mediaType, "text/") && !strings.HasPrefix(mediaType, "multipart/")) {
+		name := dispositionParams["filename"]
+		if name == "" {
+			name = params["name"]
+		}
+		if decoded, err := e.decoder.DecodeHeader(name); err == nil {
+			name = decoded
+		}
+		if name == "" {
+			name = mediaType
+		}
+		e.attachments = append(e.attachments, name)
+		return nil
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		return e.walkMultipart(mediaType, params["boundary"], body)
+	}
+
+	text, err := decodePart(header, params["charset"], body)
+	if err != nil {
+		return err
+	}
+
+	if mediaType == "text/html" {
+		e.body.Write(text)
+	} else {
+		e.body.WriteString("<pre>")
+		e.body.WriteString(html.EscapeString(string(text)))
+		e.body.WriteString("</pre>")
+	}
+	return nil
+}
+
+// walkMultipart обходит составную часть письма. Из multipart/alternative берётся
+// последняя поддерживаемая часть (по RFC 2046 — наиболее точная, обычно HTML)
+func (e *emailBody) walkMultipart(mediaType, boundary string, body io.Reader) error {
+	if boundary == "" {
+		return fmt.Errorf("multipart boundary is missing")
+	}
+
+	reader := multipart.NewReader(body, boundary)
+	var alternative *emailBody
+	for {
+		part, err := reader.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if mediaType != "multipart/alternative" {
+			if err := e.walk(part.Header, part); err != nil {
+				return err
+			}
+			continue
+		}
+
+		candidate := &emailBody{decoder: e.decoder}
+		if err := candidate.walk(part.Header, part); err != nil {
+			return err
+		}
+		if candidate.body.Len() > 0 {
+			alternative = candidate
+		}
+	}
+
+	if alternative != nil {
+		e.body.Write(alternative.body.Bytes())
+		e.attachments = append(e.attachments, alternative.attachments...)
+	}
+	return nil
+}
+
+// decodePart декодирует содержимое текстовой части письма в UTF-8
+func decodePart(header emailHeader, charset string, body io.Reader) ([]byte, error) {
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	if charset != "" {
+		reader, err := charsetReader(charset, body)
+		if err == nil {
+			body = reader
+		}
+	}
+
+	return io.ReadAll(io.LimitReader(body, maxConvertedPartSize))
+}
+
+// charsetReader декодирует текст в кодировке charset (windows-1251, koi8-r и др.) в UTF-8
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q: %w", charset, err)
+	}
+	return encoding.NewDecoder().Reader(input), nil
+}
@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"go.uber.org/zap"
+)
+
+// unknownDocumentType ответ модели, когда документ не подходит ни к одному типу
+const unknownDocumentType = "unknown"
+
+// ClassifyDocument определяет, к какому из типов документов (шаблонов) относится документ.
+// Если модель не выбрала ни один из предложенных типов, возвращается пустой тип
+func (c *OllamaClient) ClassifyDocument(ctx context.Context, images []io.Reader, text string, types []*domain.Template) (*domain.Classification, error) {
+	if len(types) == 0 {
+		return nil, domain.ErrNoDocumentTypes
+	}
+
+	c.logger.Debug("Starting document classification",
+		zap.String("model", c.model),
+		zap.Int("image_count", len(images)),
+		zap.Int("text_length", len(text)),
+		zap.Int("type_count", len(types)),
+	)
+
+	placeholders := make([]string, len(images))
+	for i := range placeholders {
+		placeholders[i] = imagePlaceholder
+	}
+
+	prompt, err := c.buildClassifyPrompt(types, text, len(images) > 0)
+	if err != nil {
+		return nil, err
+	}
+
+	message := map[string]any{
+		"role":    "user",
+		"content": prompt,
+	}
+	if len(images) > 0 {
+		message["images"] = placeholders
+	}
+
+	reqBody := map[string]any{
+		"model":    c.model,
+		"messages": []map[string]any{message},
+		"stream":   false,
+		"format":   "json",
+		"options": map[string]any{
+			"temperature": 0,
+			"num_predict": 256,
+		},
+	}
+
+	chatResp, err := c.chat(ctx, reqBody, images)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Raw LLM classification response", zap.String("response", chatResp.Message.Content))
+
+	classification, err := parseClassification(chatResp.Message.Content, types)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse LLM classification: %w", err)
+	}
+
+	return classification, nil
+}
+
+// buildClassifyPrompt формирует промпт классификации со списком типов документов
+func (c *OllamaClient) buildClassifyPrompt(types []*domain.Template, text string, hasImages bool) (string, error) {
+	return c.prompts.classification().render(classifyData{
+		Source:    promptSource(hasImages),
+		HasImages: hasImages,
+		Types:     types,
+		Unknown:   unknownDocumentType,
+		Text:      text,
+	})
+}
+
+// parseClassification разбирает ответ модели. Тип, которого нет среди предложенных,
+// считается неопознанным; уверенность ограничивается диапазоном [0, 1]
+func parseClassification(response string, types []*domain.Template) (*domain.Classification, error) {
+	startIdx := strings.Index(response, "{")
+	endIdx := strings.LastIndex(response, "}")
+	if startIdx == -1 || endIdx == -1 || startIdx > endIdx {
+		return nil, fmt.Errorf("no valid JSON found in response")
+	}
+
+	var answer struct {
+		DocumentType string  `json:"document_type"`
+		Confidence   float64 `json:"confidence"`
+	}
+	if err := json.Unmarshal([]byte(response[startIdx:endIdx+1]), &answer); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	classification := &domain.Classification{Confidence: min(max(answer.Confidence, 0), 1)}
+	name := strings.TrimSpace(answer.DocumentType)
+	for _, t := range types {
+		if strings.EqualFold(t.Name, name) {
+			classification.DocumentType = t.Name
+			return classification, nil
+		}
+	}
+
+	classification.Confidence = 0
+	return classification, nil
+}
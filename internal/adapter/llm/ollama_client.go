@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,25 +13,41 @@ import (
 	"time"
 
 	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/pkg/metrics"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// imagePlaceholder заменяется в JSON запроса потоком base64 изображения
+const imagePlaceholder = "\x00image\x00"
+
+// promptVersion версия встроенных промптов; увеличивается при изменении prompts/*.tmpl,
+// чтобы не использовать закэшированные результаты старого промпта
+const promptVersion = "v1"
+
 // OllamaClient клиент для работы с Ollama API
 type OllamaClient struct {
 	httpClient *http.Client
 	baseURL    string
 	model      string
+	prompts    *PromptSet
 	logger     *zap.Logger
 }
 
 // NewOllamaClient создаёт новый экземпляр OllamaClient
-func NewOllamaClient(cfg config.OllamaConfig, logger *zap.Logger) *OllamaClient {
+func NewOllamaClient(cfg config.OllamaConfig, prompts *PromptSet, logger *zap.Logger) *OllamaClient {
 	return &OllamaClient{
 		httpClient: &http.Client{
 			Timeout: cfg.RequestTimeout,
 		},
 		baseURL: cfg.Host,
 		model:   cfg.Model,
+		prompts: prompts,
 		logger:  logger,
 	}
 }
@@ -60,75 +77,146 @@ type ollamaResponse struct {
 
 // RecognizeDocument распознаёт документ и извлекает данные по схеме
 // RecognizeDocument распознаёт документ с помощью vision модели
-func (c *OllamaClient) RecognizeDocument(ctx context.Context, imageData []byte, contentType string, schema []string) (map[string]any, error) {
+// Несколько изображений (страницы одного документа) отправляются в одном запросе.
+// Текстовый слой PDF добавляется в промпт; без изображений распознаётся только текст.
+// Промпт выбирается по типу документа из контекста промпта
+func (c *OllamaClient) RecognizeDocument(ctx context.Context, images []io.Reader, text string, contentType string, schema []string, promptCtx domain.PromptContext) (map[string]any, error) {
 	c.logger.Debug("Starting document recognition",
 		zap.String("model", c.model),
-		zap.Int("image_size", len(imageData)),
+		zap.Int("image_count", len(images)),
+		zap.Int("text_length", len(text)),
 		zap.Strings("schema", schema),
 	)
 
+	parsed, err := domain.ParseSchema(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	// Таблицы с большим числом строк требуют длинного ответа
+	numPredict := 2048
+	if parsed.HasTables() {
+		numPredict = 8192
+	}
+
+	placeholders := make([]string, len(images))
+	for i := range placeholders {
+		placeholders[i] = imagePlaceholder
+	}
+
 	// Формируем промпт
-	prompt := c.buildPrompt(schema)
+	prompt, err := c.buildPrompt(schema, parsed, text, len(images) > 0, promptCtx)
+	if err != nil {
+		return nil, err
+	}
 
-	// Кодируем изображение в base64
-	imageBase64 := base64.StdEncoding.EncodeToString(imageData)
+	message := map[string]any{
+		"role":    "user",
+		"content": prompt,
+	}
+	if len(images) > 0 {
+		message["images"] = placeholders
+	}
 
 	// Формируем запрос для /api/chat (vision модели)
 	reqBody := map[string]any{
-		"model": c.model,
-		"messages": []map[string]any{
-			{
-				"role":    "user",
-				"content": prompt,
-				"images":  []string{imageBase64},
-			},
-		},
+		"model":    c.model,
+		"messages": []map[string]any{message},
 		"stream": false,
 		"format": "json",
 		"options": map[string]any{
 			"temperature": 0.1,
-			"num_predict": 2048,
+			"num_predict": numPredict,
 		},
 	}
 
-	reqJSON, err := json.Marshal(reqBody)
+	chatResp, err := c.chat(ctx, reqBody, images)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
+	}
+
+	// Логируем ответ модели для отладки
+	c.logger.Debug("Raw LLM response", zap.String("response", chatResp.Message.Content))
+
+	// Парсим JSON из ответа
+	result, err := c.parseResponse(chatResp.Message.Content, parsed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
+	}
+
+	return result, nil
+}
+
+// chatResponse ответ Ollama /api/chat
+type chatResponse struct {
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	PromptEvalCount int    `json:"prompt_eval_count"` // Токены промпта
+	EvalCount       int    `json:"eval_count"`        // Токены ответа
+	Error           string `json:"error,omitempty"`
+}
+
+// chat отправляет запрос к /api/chat и учитывает его в метриках
+func (c *OllamaClient) chat(ctx context.Context, reqBody map[string]any, images []io.Reader) (*chatResponse, error) {
+	ctx, span := tracing.Tracer().Start(ctx, "ollama.chat",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("gen_ai.request.model", c.model)),
+	)
+	defer span.End()
+
+	startTime := time.Now()
+	chatResp, err := c.doChat(ctx, reqBody, images)
+	metrics.ObserveLLMRequest(c.model, time.Since(startTime), err)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	metrics.AddLLMTokens(c.model, chatResp.PromptEvalCount, chatResp.EvalCount)
+	span.SetAttributes(
+		attribute.Int("gen_ai.usage.input_tokens", chatResp.PromptEvalCount),
+		attribute.Int("gen_ai.usage.output_tokens", chatResp.EvalCount),
+	)
+
+	c.logger.Debug("Ollama request completed",
+		zap.Duration("duration", time.Since(startTime)),
+		zap.Int("prompt_tokens", chatResp.PromptEvalCount),
+		zap.Int("completion_tokens", chatResp.EvalCount),
+	)
+
+	return chatResp, nil
+}
+
+// doChat выполняет HTTP запрос к /api/chat
+func (c *OllamaClient) doChat(ctx context.Context, reqBody map[string]any, images []io.Reader) (*chatResponse, error) {
+	body, err := requestBody(reqBody, images)
+	if err != nil {
+		return nil, err
 	}
 
 	// Отправляем запрос к /api/chat
 	url := fmt.Sprintf("%s/api/chat", c.baseURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqJSON))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 
-	startTime := time.Now()
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request to Ollama: %w", err)
 	}
 	defer resp.Body.Close()
 
-	c.logger.Debug("Ollama request completed",
-		zap.Duration("duration", time.Since(startTime)),
-		zap.Int("status_code", resp.StatusCode),
-	)
-
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("ollama returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	// Парсим ответ
-	var chatResp struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
-		Error string `json:"error,omitempty"`
-	}
-
+	var chatResp chatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
@@ -137,48 +225,114 @@ func (c *OllamaClient) RecognizeDocument(ctx context.Context, imageData []byte,
 		return nil, fmt.Errorf("ollama error: %s", chatResp.Error)
 	}
 
-	// Логируем ответ модели для отладки
-	c.logger.Debug("Raw LLM response", zap.String("response", chatResp.Message.Content))
+	return &chatResp, nil
+}
 
-	// Парсим JSON из ответа
-	result, err := c.parseResponse(chatResp.Message.Content, schema)
+// requestBody формирует тело запроса. Изображения кодируются в base64 по мере
+// отправки, чтобы не держать в памяти их закодированные копии.
+// Каждое вхождение imagePlaceholder заменяется очередным изображением
+func requestBody(reqBody map[string]any, images []io.Reader) (io.Reader, error) {
+	reqJSON, err := json.Marshal(reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	return result, nil
+	placeholder, _ := json.Marshal(imagePlaceholder)
+	parts := bytes.Split(reqJSON, placeholder)
+	if len(parts)-1 != len(images) {
+		return nil, fmt.Errorf("request has %d image placeholders for %d images", len(parts)-1, len(images))
+	}
+
+	readers := []io.Reader{bytes.NewReader(parts[0])}
+	for i, image := range images {
+		readers = append(readers,
+			strings.NewReader(`"`),
+			newBase64Reader(image),
+			strings.NewReader(`"`),
+			bytes.NewReader(parts[i+1]),
+		)
+	}
+
+	return io.MultiReader(readers...), nil
 }
 
-// buildPrompt формирует промпт для распознавания документа
-func (c *OllamaClient) buildPrompt(schema []string) string {
-	fieldsJSON, _ := json.Marshal(schema)
-	
-	prompt := fmt.Sprintf(`You are a document recognition assistant. Analyze the provided document image and extract the requested information.
+// base64Reader кодирует поток в base64 по мере чтения
+type base64Reader struct {
+	src io.Reader
+	in  []byte
+	out []byte
+	eof bool
+}
+
+func newBase64Reader(src io.Reader) *base64Reader {
+	// Размер блока кратен 3, чтобы выравнивание появлялось только в конце
+	return &base64Reader{src: src, in: make([]byte, 3*4096)}
+}
+
+func (r *base64Reader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(r.src, r.in)
+		switch {
+		case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			r.eof = true
+		case err != nil:
+			return 0, err
+		}
+		r.out = base64.StdEncoding.AppendEncode(r.out[:0], r.in[:n])
+	}
 
-TASK: Extract the following fields from the document:
-%s
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
 
-INSTRUCTIONS:
-1. Carefully analyze the document image
-2. Extract values for each requested field
-3. If a field is not found or not applicable, use null
-4. For dates, use ISO 8601 format (YYYY-MM-DD)
-5. For monetary amounts, extract the numeric value only
-6. Return ONLY valid JSON, no additional text
+// Model возвращает имя используемой модели
+func (c *OllamaClient) Model() string {
+	return c.model
+}
 
-RESPONSE FORMAT:
-Return a JSON object with the requested fields as keys and extracted values.
+// PromptVersion возвращает версию промпта распознавания для типа документа
+func (c *OllamaClient) PromptVersion(promptCtx domain.PromptContext) string {
+	return c.prompts.recognition(promptCtx.DocumentType).version
+}
 
-Example for fields ["invoice_number", "date", "total_amount"]:
-{"invoice_number": "INV-2024-001", "date": "2024-01-15", "total_amount": 1500.00}
+// buildPrompt формирует промпт для распознавания документа по шаблону типа документа.
+// Для схемы с таблицами в список полей попадают скалярные поля и имена таблиц
+func (c *OllamaClient) buildPrompt(schema []string, parsed domain.Schema, text string, hasImages bool, promptCtx domain.PromptContext) (string, error) {
+	if parsed.HasTables() {
+		schema = append([]string{}, parsed.Fields...)
+		for _, table := range parsed.Tables {
+			schema = append(schema, table.Name)
+		}
+	}
 
-Now analyze the document and extract: %s`, string(fieldsJSON), strings.Join(schema, ", "))
+	examples := make([]string, 0, len(promptCtx.Examples))
+	for _, example := range promptCtx.Examples {
+		data, err := json.Marshal(example)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode prompt example: %w", err)
+		}
+		examples = append(examples, string(data))
+	}
 
-	return prompt
+	return c.prompts.recognition(promptCtx.DocumentType).render(recognizeData{
+		Source:       promptSource(hasImages),
+		HasImages:    hasImages,
+		Fields:       schema,
+		Tables:       parsed.Tables,
+		DocumentType: promptCtx.DocumentType,
+		Language:     promptCtx.Language,
+		Examples:     examples,
+		Text:         text,
+	})
 }
 
 // parseResponse парсит ответ LLM и извлекает JSON
-func (c *OllamaClient) parseResponse(response string, schema []string) (map[string]any, error) {
+func (c *OllamaClient) parseResponse(response string, schema domain.Schema) (map[string]any, error) {
 	// Очищаем ответ от возможных markdown блоков
 	response = strings.TrimSpace(response)
 	response = strings.TrimPrefix(response, "```json")
@@ -201,12 +355,9 @@ func (c *OllamaClient) parseResponse(response string, schema []string) (map[stri
 		return nil, fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	// Проверяем, что все запрошенные поля присутствуют (добавляем null если нет)
-	for _, field := range schema {
-		if _, ok := result[field]; !ok {
-			result[field] = nil
-		}
-	}
+	// Проверяем, что все запрошенные поля присутствуют (добавляем null если нет),
+	// и приводим строки таблиц к объявленным колонкам
+	schema.Normalize(result)
 
 	return result, nil
 }
@@ -258,7 +409,7 @@ func (c *OllamaClient) CheckModel(ctx context.Context) error {
 
 	for _, model := range tagsResp.Models {
 		if strings.HasPrefix(model.Name, strings.Split(c.model, ":")[0]) {
-			c.logger.Info("Model found", zap.String("model", model.Name))
+			c.logger.Debug("Model found", zap.String("model", model.Name))
 			return nil
 		}
 	}
@@ -2,15 +2,27 @@ package llm
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"image/png"
 	"io"
+	"os"
+	"time"
 
 	"github.com/gen2brain/go-fitz"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/internal/usecase"
+	"github.com/plastinin/docrecognizer/pkg/metrics"
 )
 
-// PDFConverter конвертирует PDF в изображения
+// errPasswordUnsupported сборка без cgo или с несовместимой версией go-fitz
+// не умеет разблокировать зашифрованные документы
+var errPasswordUnsupported = domain.ErrPasswordUnsupported
+
+// PDFConverter конвертирует PDF и другие документы в изображения.
+// Формат определяется MuPDF по расширению файла: PDF, DOCX, XLSX, HTML, TXT, XPS, EPUB.
+// ODT и EML предварительно конвертируются в HTML
 type PDFConverter struct{}
 
 // NewPDFConverter создаёт новый конвертер
@@ -18,62 +30,109 @@ func NewPDFConverter() *PDFConverter {
 	return &PDFConverter{}
 }
 
-// ConvertFirstPage конвертирует первую страницу PDF в PNG
-func (c *PDFConverter) ConvertFirstPage(pdfData []byte) ([]byte, error) {
-	doc, err := fitz.NewFromMemory(pdfData)
+// Open открывает документ с диска. Документ читается по мере рендеринга страниц,
+// а не загружается в память целиком. Пароль нужен только для зашифрованных PDF
+func (c *PDFConverter) Open(path string, password string) (usecase.PDFDocument, error) {
+	converted, err := convertToHTML(path)
+	if err != nil {
+		return nil, err
+	}
+	if converted != "" {
+		path = converted
+	}
+
+	doc, err := fitz.New(path)
+	if errors.Is(err, fitz.ErrNeedsPassword) {
+		if err = unlock(doc, password); err != nil {
+			doc.Close()
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to open PDF: %w", err)
+		removeConverted(converted)
+		return nil, fmt.Errorf("failed to open document: %w", err)
 	}
-	defer doc.Close()
 
 	if doc.NumPage() == 0 {
-		return nil, fmt.Errorf("PDF has no pages")
+		doc.Close()
+		removeConverted(converted)
+		return nil, fmt.Errorf("document has no pages")
 	}
 
-	// Конвертируем первую страницу
-	img, err := doc.Image(0)
+	return &pdfDocument{doc: doc, converted: converted}, nil
+}
+
+// unlock разблокирует зашифрованный PDF паролем
+func unlock(doc *fitz.Document, password string) error {
+	if password == "" {
+		return domain.ErrPasswordRequired
+	}
+	ok, err := authenticatePassword(doc, password)
 	if err != nil {
-		return nil, fmt.Errorf("failed to render page: %w", err)
+		return err
 	}
+	if !ok {
+		return domain.ErrInvalidPassword
+	}
+	return nil
+}
 
-	// Кодируем в PNG
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
-		return nil, fmt.Errorf("failed to encode PNG: %w", err)
+// removeConverted удаляет промежуточный HTML файл
+func removeConverted(path string) {
+	if path != "" {
+		os.Remove(path)
 	}
+}
 
-	return buf.Bytes(), nil
+// pdfDocument открытый документ
+type pdfDocument struct {
+	doc       *fitz.Document
+	converted string // Промежуточный HTML файл для ODT и EML
 }
 
-// ConvertAllPages конвертирует все страницы PDF в изображения
-func (c *PDFConverter) ConvertAllPages(pdfData []byte) ([][]byte, error) {
-	doc, err := fitz.NewFromMemory(pdfData)
+// NumPages возвращает количество страниц
+func (d *pdfDocument) NumPages() int {
+	return d.doc.NumPage()
+}
+
+// PageSize возвращает размер страницы в точках (1/72 дюйма)
+func (d *pdfDocument) PageSize(page int) (float64, float64, error) {
+	bounds, err := d.doc.Bound(page)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open PDF: %w", err)
+		return 0, 0, fmt.Errorf("failed to get page %d size: %w", page, err)
 	}
-	defer doc.Close()
+	return float64(bounds.Dx()), float64(bounds.Dy()), nil
+}
 
-	numPages := doc.NumPage()
-	if numPages == 0 {
-		return nil, fmt.Errorf("PDF has no pages")
+// RenderPage рендерит страницу с заданным разрешением и пишет её в w в формате PNG
+func (d *pdfDocument) RenderPage(page int, dpi float64, w io.Writer) error {
+	start := time.Now()
+	img, err := d.doc.ImageDPI(page, dpi)
+	metrics.ObservePDFRender(time.Since(start))
+	if err != nil {
+		return fmt.Errorf("failed to render page %d: %w", page, err)
 	}
 
-	images := make([][]byte, 0, numPages)
-	for i := 0; i < numPages; i++ {
-		img, err := doc.Image(i)
-		if err != nil {
-			return nil, fmt.Errorf("failed to render page %d: %w", i, err)
-		}
+	if err := png.Encode(w, img); err != nil {
+		return fmt.Errorf("failed to encode page %d: %w", page, err)
+	}
 
-		var buf bytes.Buffer
-		if err := png.Encode(&buf, img); err != nil {
-			return nil, fmt.Errorf("failed to encode page %d: %w", i, err)
-		}
+	return nil
+}
 
-		images = append(images, buf.Bytes())
+// PageText извлекает текстовый слой страницы. Для отсканированных страниц он пустой
+func (d *pdfDocument) PageText(page int) (string, error) {
+	text, err := d.doc.Text(page)
+	if err != nil {
+		return "", fmt.Errorf("failed to extract page %d text: %w", page, err)
 	}
+	return text, nil
+}
 
-	return images, nil
+// Close закрывает документ и удаляет промежуточный файл
+func (d *pdfDocument) Close() error {
+	err := d.doc.Close()
+	removeConverted(d.converted)
+	return err
 }
 
 // ImageToBytes конвертирует image.Image в PNG bytes
@@ -88,4 +147,4 @@ func ImageToBytes(img image.Image) ([]byte, error) {
 // ReadAllBytes читает все байты из reader
 func ReadAllBytes(reader io.Reader) ([]byte, error) {
 	return io.ReadAll(reader)
-}
\ No newline at end of file
+}
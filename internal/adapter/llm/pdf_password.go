@@ -0,0 +1,76 @@
+//go:build cgo && !nocgo
+
+package llm
+
+/*
+#include <stdlib.h>
+
+typedef struct fz_context fz_context;
+typedef struct fz_document fz_document;
+
+int fz_authenticate_password(fz_context *ctx, fz_document *doc, const char *password);
+*/
+import "C"
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// fitzDocument повторяет раскладку fitz.Document go-fitz v1.24.15: go-fitz не предоставляет
+// fz_authenticate_password, а функции MuPDF нужны указатели контекста и документа.
+// Весь доступ к MuPDF в обход go-fitz сосредоточен в этом файле
+type fitzDocument struct {
+	ctx    unsafe.Pointer
+	data   []byte
+	doc    unsafe.Pointer
+	mtx    sync.Mutex
+	stream unsafe.Pointer
+}
+
+// Проверка при сборке: размер fitz.Document совпадает с fitzDocument.
+// При обновлении go-fitz с другой раскладкой сборка упадёт здесь
+var _ = [1]struct{}{}[unsafe.Sizeof(fitz.Document{})-unsafe.Sizeof(fitzDocument{})]
+
+// fitzLayoutMatches проверяет при старте, что имена, порядок, смещения и виды полей
+// fitz.Document совпадают с fitzDocument. Иначе разблокировка паролем отключается
+var fitzLayoutMatches = func() bool {
+	actual, expected := reflect.TypeOf(fitz.Document{}), reflect.TypeOf(fitzDocument{})
+	if actual.NumField() != expected.NumField() {
+		return false
+	}
+	for i := range expected.NumField() {
+		a, e := actual.Field(i), expected.Field(i)
+		if a.Name != e.Name || a.Offset != e.Offset || a.Type.Size() != e.Type.Size() {
+			return false
+		}
+		if a.Type.Kind() != e.Type.Kind() && !(a.Type.Kind() == reflect.Pointer && e.Type.Kind() == reflect.UnsafePointer) {
+			return false
+		}
+	}
+	return true
+}()
+
+// authenticatePassword разблокирует зашифрованный документ паролем.
+// Вызов выполняется под мьютексом документа, как и вызовы MuPDF внутри go-fitz
+func authenticatePassword(doc *fitz.Document, password string) (bool, error) {
+	if !fitzLayoutMatches {
+		return false, errPasswordUnsupported
+	}
+
+	d := (*fitzDocument)(unsafe.Pointer(doc))
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.ctx == nil || d.doc == nil {
+		return false, errPasswordUnsupported
+	}
+
+	cpassword := C.CString(password)
+	defer C.free(unsafe.Pointer(cpassword))
+
+	return C.fz_authenticate_password((*C.fz_context)(d.ctx), (*C.fz_document)(d.doc), cpassword) != 0, nil
+}
@@ -0,0 +1,10 @@
+//go:build !cgo || nocgo
+
+package llm
+
+import "github.com/gen2brain/go-fitz"
+
+// authenticatePassword без cgo недоступна: go-fitz не предоставляет разблокировку паролем
+func authenticatePassword(doc *fitz.Document, password string) (bool, error) {
+	return false, errPasswordUnsupported
+}
@@ -0,0 +1,12 @@
+//go:build cgo && !nocgo
+
+package llm
+
+import "testing"
+
+// При обновлении go-fitz раскладка fitz.Document должна остаться совместимой с fitzDocument
+func TestFitzDocumentLayout(t *testing.T) {
+	if !fitzLayoutMatches {
+		t.Fatal("fitz.Document layout does not match fitzDocument, password unlocking is disabled")
+	}
+}
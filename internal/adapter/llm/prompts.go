@@ -0,0 +1,170 @@
+package llm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/plastinin/docrecognizer/internal/domain"
+)
+
+// builtinPrompts промпты по умолчанию; текст совпадает с версией promptVersion
+//
+//go:embed prompts/*.tmpl
+var builtinPrompts embed.FS
+
+// promptFuncs функции, доступные в шаблонах промптов
+var promptFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"join": strings.Join,
+}
+
+// prompt шаблон промпта и его версия
+type prompt struct {
+	tmpl    *template.Template
+	version string
+}
+
+// PromptSet промпты модели: встроенные, из каталога и из БД.
+// Промпт с именем типа документа заменяет промпт распознавания для этого типа
+type PromptSet struct {
+	prompts map[string]*prompt
+}
+
+// NewPromptSet создаёт набор со встроенными промптами
+func NewPromptSet() (*PromptSet, error) {
+	set := &PromptSet{prompts: make(map[string]*prompt)}
+	for _, name := range []string{domain.PromptRecognize, domain.PromptClassify} {
+		body, err := builtinPrompts.ReadFile("prompts/" + name + ".tmpl")
+		if err != nil {
+			return nil, err
+		}
+		tmpl, err := parsePrompt(name, string(body))
+		if err != nil {
+			return nil, err
+		}
+		set.prompts[name] = &prompt{tmpl: tmpl, version: promptVersion}
+	}
+	return set, nil
+}
+
+// LoadDir загружает промпты из файлов <имя>.tmpl каталога. Пустой путь — каталог не настроен
+func (s *PromptSet) LoadDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		return fmt.Errorf("failed to list prompt templates: %w", err)
+	}
+	for _, path := range paths {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read prompt template: %w", err)
+		}
+		if err := s.Add(strings.TrimSuffix(filepath.Base(path), ".tmpl"), string(body)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add добавляет промпт, заменяя промпт с тем же именем. Версия промпта —
+// имя и хэш его текста, чтобы по задаче можно было восстановить использованный промпт.
+// Шаблон проверяется пробным рендерингом, чтобы ошибки в переменных обнаружились при старте
+func (s *PromptSet) Add(name, body string) error {
+	tmpl, err := parsePrompt(name, body)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256([]byte(body))
+	p := &prompt{tmpl: tmpl, version: name + "@" + hex.EncodeToString(sum[:6])}
+
+	var sample any = recognizeData{Source: promptSource(true), HasImages: true, Fields: []string{"total_amount"}}
+	if name == domain.PromptClassify {
+		sample = classifyData{Source: promptSource(true), HasImages: true, Unknown: unknownDocumentType}
+	}
+	if _, err := p.render(sample); err != nil {
+		return fmt.Errorf("%w: %w", domain.ErrInvalidPromptTemplate, err)
+	}
+
+	s.prompts[name] = p
+	return nil
+}
+
+// Len возвращает количество промптов
+func (s *PromptSet) Len() int {
+	return len(s.prompts)
+}
+
+// recognition возвращает промпт распознавания для типа документа
+func (s *PromptSet) recognition(documentType string) *prompt {
+	if p, ok := s.prompts[documentType]; ok && documentType != domain.PromptClassify {
+		return p
+	}
+	return s.prompts[domain.PromptRecognize]
+}
+
+// classification возвращает промпт классификации
+func (s *PromptSet) classification() *prompt {
+	return s.prompts[domain.PromptClassify]
+}
+
+// parsePrompt разбирает шаблон промпта
+func parsePrompt(name, body string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(promptFuncs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %w", domain.ErrInvalidPromptTemplate, name, err)
+	}
+	return tmpl, nil
+}
+
+// render формирует текст промпта
+func (p *prompt) render(data any) (string, error) {
+	var b bytes.Buffer
+	if err := p.tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %q: %w", p.tmpl.Name(), err)
+	}
+	return b.String(), nil
+}
+
+// recognizeData переменные промпта распознавания
+type recognizeData struct {
+	Source       string
+	HasImages    bool
+	Fields       []string
+	Tables       []domain.TableField
+	DocumentType string
+	Language     string
+	Examples     []string
+	Text         string
+}
+
+// classifyData переменные промпта классификации
+type classifyData struct {
+	Source    string
+	HasImages bool
+	Types     []*domain.Template
+	Unknown   string
+	Text      string
+}
+
+// promptSource описание входных данных модели для промпта
+func promptSource(hasImages bool) string {
+	if hasImages {
+		return "document image"
+	}
+	return "document text"
+}
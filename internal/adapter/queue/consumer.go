@@ -3,46 +3,52 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/internal/domain"
 	"github.com/plastinin/docrecognizer/internal/usecase"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 // TaskConsumer обрабатывает задачи из очереди
 type TaskConsumer struct {
-	server            *asynq.Server
-	mux               *asynq.ServeMux
-	recognitionUC     *usecase.RecognitionUseCase
-	logger            *zap.Logger
+	redis         *redis.Client // Соединение, общее с server; используется для проверки с учётом контекста
+	server        *asynq.Server
+	mux           *asynq.ServeMux
+	recognitionUC *usecase.RecognitionUseCase
+	logger        *zap.Logger
 }
 
 // NewTaskConsumer создаёт новый экземпляр TaskConsumer
 func NewTaskConsumer(
 	cfg config.RedisConfig,
+	workerCfg config.WorkerConfig,
 	recognitionUC *usecase.RecognitionUseCase,
 	logger *zap.Logger,
 ) *TaskConsumer {
-	server := asynq.NewServer(
-		asynq.RedisClientOpt{
-			Addr:     cfg.Addr(),
-			Password: cfg.Password,
-			DB:       cfg.DB,
-		},
+	rdb := newRedisClient(cfg)
+	server := asynq.NewServerFromRedisClient(
+		rdb,
 		asynq.Config{
-			Concurrency: 2, // Количество одновременных воркеров (для CPU режима лучше меньше)
-			Queues: map[string]int{
-				"recognition": 10, // Приоритет очереди
-				"default":     1,
-			},
-			Logger: newAsynqLogger(logger),
+			Concurrency:     workerCfg.Concurrency,
+			Queues:          workerCfg.Queues,
+			StrictPriority:  workerCfg.StrictPriority,
+			RetryDelayFunc:  newRetryDelayFunc(workerCfg),
+			ShutdownTimeout: workerCfg.ShutdownTimeout,
+			Logger:          newAsynqLogger(logger),
 		},
 	)
 
 	consumer := &TaskConsumer{
+		redis:         rdb,
 		server:        server,
 		mux:           asynq.NewServeMux(),
 		recognitionUC: recognitionUC,
@@ -66,6 +72,18 @@ func (c *TaskConsumer) Stop() {
 	c.logger.Info("Stopping task consumer")
 	c.server.Stop()
 	c.server.Shutdown()
+	// Общее соединение asynq.Server не закрывает
+	if err := c.redis.Close(); err != nil {
+		c.logger.Warn("Failed to close redis connection", zap.Error(err))
+	}
+}
+
+// CheckHealth проверяет доступность Redis
+func (c *TaskConsumer) CheckHealth(ctx context.Context) error {
+	if err := c.redis.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to ping redis: %w", err)
+	}
+	return nil
 }
 
 // handleDocumentRecognition обрабатывает задачу распознавания документа
@@ -88,15 +106,46 @@ func (c *TaskConsumer) handleDocumentRecognition(ctx context.Context, t *asynq.T
 		return fmt.Errorf("invalid task ID: %w", err)
 	}
 
+	// Определяем, последняя ли это попытка: после неё задача уйдёт в архив
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+
+	// Продолжаем трассировку, начатую при загрузке документа
+	ctx = tracing.Extract(ctx, payload.TraceContext)
+	ctx, span := tracing.Tracer().Start(ctx, "queue.Process "+t.Type(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "asynq"),
+			attribute.String("task_id", taskID.String()),
+			attribute.String("request_id", payload.RequestID),
+			attribute.Int("retried", retried),
+		),
+	)
+	defer span.End()
+
 	c.logger.Info("Processing document recognition task",
 		zap.String("task_id", taskID.String()),
+		zap.String("request_id", payload.RequestID),
+		zap.String("trace_id", tracing.TraceID(ctx)),
+		zap.Int("retried", retried),
+		zap.Int("max_retry", maxRetry),
 	)
 
-	if err := c.recognitionUC.ProcessTask(ctx, taskID); err != nil {
+	input := usecase.ProcessTaskInput{
+		TaskID:      taskID,
+		LastAttempt: retried >= maxRetry,
+	}
+
+	if err := c.recognitionUC.ProcessTask(ctx, input); err != nil {
+		tracing.RecordError(span, err)
 		c.logger.Error("Failed to process task",
 			zap.String("task_id", taskID.String()),
 			zap.Error(err),
 		)
+		// Повтор не поможет: сразу отправляем задачу в архив
+		if errors.Is(err, domain.ErrNonRetryable) {
+			return fmt.Errorf("%w: %w", err, asynq.SkipRetry)
+		}
 		return err
 	}
 
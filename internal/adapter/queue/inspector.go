@@ -0,0 +1,190 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/internal/domain"
+)
+
+// QueueInspector инспектирует очередь распознавания через asynq.Inspector
+type QueueInspector struct {
+	inspector *asynq.Inspector
+	queue     string
+}
+
+// NewQueueInspector создаёт новый экземпляр QueueInspector
+func NewQueueInspector(cfg config.RedisConfig, queue string) *QueueInspector {
+	inspector := asynq.NewInspector(asynq.RedisClientOpt{
+		Addr:     cfg.Addr(),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	return &QueueInspector{
+		inspector: inspector,
+		queue:     queue,
+	}
+}
+
+// GetQueueInfo возвращает состояние очереди
+func (i *QueueInspector) GetQueueInfo(_ context.Context) (*domain.QueueInfo, error) {
+	info, err := i.inspector.GetQueueInfo(i.queue)
+	if err != nil {
+		// Очередь появляется в Redis только после первой задачи
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			return &domain.QueueInfo{Queue: i.queue}, nil
+		}
+		return nil, fmt.Errorf("failed to get queue info: %w", err)
+	}
+
+	return &domain.QueueInfo{
+		Queue:     info.Queue,
+		Size:      info.Size,
+		Pending:   info.Pending,
+		Active:    info.Active,
+		Scheduled: info.Scheduled,
+		Retry:     info.Retry,
+		Archived:  info.Archived,
+		Completed: info.Completed,
+		Processed: info.Processed,
+		Failed:    info.Failed,
+		Paused:    info.Paused,
+		Latency:   info.Latency,
+	}, nil
+}
+
+// ListTasks возвращает задачи очереди в указанном состоянии
+func (i *QueueInspector) ListTasks(_ context.Context, state domain.QueueTaskState, pagination domain.Pagination) ([]*domain.QueueTask, error) {
+	opts := []asynq.ListOption{
+		asynq.Page(pagination.Page),
+		asynq.PageSize(pagination.PageSize),
+	}
+
+	var (
+		infos []*asynq.TaskInfo
+		err   error
+	)
+
+	switch state {
+	case domain.QueueTaskStatePending:
+		infos, err = i.inspector.ListPendingTasks(i.queue, opts...)
+	case domain.QueueTaskStateActive:
+		infos, err = i.inspector.ListActiveTasks(i.queue, opts...)
+	case domain.QueueTaskStateScheduled:
+		infos, err = i.inspector.ListScheduledTasks(i.queue, opts...)
+	case domain.QueueTaskStateRetry:
+		infos, err = i.inspector.ListRetryTasks(i.queue, opts...)
+	case domain.QueueTaskStateArchived:
+		infos, err = i.inspector.ListArchivedTasks(i.queue, opts...)
+	case domain.QueueTaskStateCompleted:
+		infos, err = i.inspector.ListCompletedTasks(i.queue, opts...)
+	default:
+		return nil, domain.ErrInvalidQueueTaskState
+	}
+
+	if err != nil {
+		if errors.Is(err, asynq.ErrQueueNotFound) {
+			return []*domain.QueueTask{}, nil
+		}
+		return nil, fmt.Errorf("failed to list %s tasks: %w", state, err)
+	}
+
+	tasks := make([]*domain.QueueTask, 0, len(infos))
+	for _, info := range infos {
+		tasks = append(tasks, queueTaskFromInfo(info))
+	}
+
+	return tasks, nil
+}
+
+// GetTask возвращает задачу очереди по её ID
+func (i *QueueInspector) GetTask(_ context.Context, id string) (*domain.QueueTask, error) {
+	info, err := i.inspector.GetTaskInfo(i.queue, id)
+	if err != nil {
+		return nil, mapInspectorError(err, "failed to get task info")
+	}
+	return queueTaskFromInfo(info), nil
+}
+
+// RunTask переводит задачу в состояние pending для немедленной обработки
+func (i *QueueInspector) RunTask(_ context.Context, id string) error {
+	if err := i.inspector.RunTask(i.queue, id); err != nil {
+		return mapInspectorError(err, "failed to run task")
+	}
+	return nil
+}
+
+// DeleteTask удаляет задачу из очереди
+func (i *QueueInspector) DeleteTask(_ context.Context, id string) error {
+	if err := i.inspector.DeleteTask(i.queue, id); err != nil {
+		return mapInspectorError(err, "failed to delete task")
+	}
+	return nil
+}
+
+// Pause приостанавливает обработку очереди
+func (i *QueueInspector) Pause(_ context.Context) error {
+	if err := i.inspector.PauseQueue(i.queue); err != nil {
+		return fmt.Errorf("failed to pause queue: %w", err)
+	}
+	return nil
+}
+
+// Resume возобновляет обработку очереди
+func (i *QueueInspector) Resume(_ context.Context) error {
+	if err := i.inspector.UnpauseQueue(i.queue); err != nil {
+		return fmt.Errorf("failed to resume queue: %w", err)
+	}
+	return nil
+}
+
+// Close закрывает соединение
+func (i *QueueInspector) Close() error {
+	return i.inspector.Close()
+}
+
+// queueTaskFromInfo конвертирует asynq.TaskInfo в доменную модель
+func queueTaskFromInfo(info *asynq.TaskInfo) *domain.QueueTask {
+	task := &domain.QueueTask{
+		ID:        info.ID,
+		Type:      info.Type,
+		Queue:     info.Queue,
+		State:     domain.QueueTaskState(info.State.String()),
+		MaxRetry:  info.MaxRetry,
+		Retried:   info.Retried,
+		LastError: info.LastErr,
+	}
+
+	// Достаём ID задачи docrecognizer из payload
+	var payload DocumentRecognitionPayload
+	if err := json.Unmarshal(info.Payload, &payload); err == nil {
+		task.TaskID = payload.TaskID
+	}
+
+	task.LastFailedAt = timePtr(info.LastFailedAt)
+	task.NextProcessAt = timePtr(info.NextProcessAt)
+
+	return task
+}
+
+// mapInspectorError преобразует ошибки asynq в доменные
+func mapInspectorError(err error, msg string) error {
+	if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+		return domain.ErrQueueTaskNotFound
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// timePtr возвращает nil для нулевого времени
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
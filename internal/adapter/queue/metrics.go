@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"errors"
+
+	"github.com/hibiken/asynq"
+	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+var (
+	queueTasksDesc = prometheus.NewDesc(
+		"docrecognizer_queue_tasks",
+		"Количество задач в очереди по состоянию",
+		[]string{"queue", "state"}, nil,
+	)
+	queueLatencyDesc = prometheus.NewDesc(
+		"docrecognizer_queue_latency_seconds",
+		"Время ожидания самой старой задачи в очереди",
+		[]string{"queue"}, nil,
+	)
+	queuePausedDesc = prometheus.NewDesc(
+		"docrecognizer_queue_paused",
+		"Признак приостановленной очереди",
+		[]string{"queue"}, nil,
+	)
+)
+
+// MetricsCollector собирает глубину очередей asynq в момент scrape
+type MetricsCollector struct {
+	inspector *asynq.Inspector
+	queues    []string
+	logger    *zap.Logger
+}
+
+// NewMetricsCollector создаёт новый экземпляр MetricsCollector
+func NewMetricsCollector(cfg config.RedisConfig, workerCfg config.WorkerConfig, logger *zap.Logger) *MetricsCollector {
+	inspector := asynq.NewInspector(asynq.RedisClientOpt{
+		Addr:     cfg.Addr(),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	queues := make([]string, 0, len(workerCfg.Queues))
+	for name := range workerCfg.Queues {
+		queues = append(queues, name)
+	}
+
+	return &MetricsCollector{
+		inspector: inspector,
+		queues:    queues,
+		logger:    logger,
+	}
+}
+
+// Describe реализует prometheus.Collector
+func (c *MetricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueTasksDesc
+	ch <- queueLatencyDesc
+	ch <- queuePausedDesc
+}
+
+// Collect реализует prometheus.Collector
+func (c *MetricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, queue := range c.queues {
+		info, err := c.inspector.GetQueueInfo(queue)
+		if err != nil {
+			// Очередь появляется в Redis только после первой задачи
+			if !errors.Is(err, asynq.ErrQueueNotFound) {
+				c.logger.Warn("Failed to collect queue metrics",
+					zap.String("queue", queue),
+					zap.Error(err),
+				)
+			}
+			continue
+		}
+
+		states := map[string]int{
+			"pending":   info.Pending,
+			"active":    info.Active,
+			"scheduled": info.Scheduled,
+			"retry":     info.Retry,
+			"archived":  info.Archived,
+			"completed": info.Completed,
+		}
+		for state, count := range states {
+			ch <- prometheus.MustNewConstMetric(queueTasksDesc, prometheus.GaugeValue, float64(count), queue, state)
+		}
+
+		paused := 0.0
+		if info.Paused {
+			paused = 1
+		}
+		ch <- prometheus.MustNewConstMetric(queueLatencyDesc, prometheus.GaugeValue, info.Latency.Seconds(), queue)
+		ch <- prometheus.MustNewConstMetric(queuePausedDesc, prometheus.GaugeValue, paused, queue)
+	}
+}
+
+// Close закрывает соединение
+func (c *MetricsCollector) Close() error {
+	return c.inspector.Close()
+}
@@ -3,11 +3,18 @@ package queue
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Типы задач
@@ -17,48 +24,122 @@ const (
 
 // DocumentRecognitionPayload данные задачи на распознавание
 type DocumentRecognitionPayload struct {
-	TaskID string `json:"task_id"`
+	TaskID    string `json:"task_id"`
+	RequestID string `json:"request_id,omitempty"` // ID HTTP запроса, создавшего задачу
+	// Контекст трассировки (W3C traceparent), чтобы спан воркера продолжал трассировку загрузки
+	TraceContext map[string]string `json:"trace_context,omitempty"`
 }
 
 // TaskProducer отправляет задачи в очередь
 type TaskProducer struct {
-	client *asynq.Client
+	redis     *redis.Client // Соединение, общее с client; используется для проверки с учётом контекста
+	client    *asynq.Client
+	inspector *asynq.Inspector // Проверка состояния уже поставленной задачи при конфликте ID
+	cfg       config.WorkerConfig
 }
 
 // NewTaskProducer создаёт новый экземпляр TaskProducer
-func NewTaskProducer(cfg config.RedisConfig) *TaskProducer {
-	client := asynq.NewClient(asynq.RedisClientOpt{
-		Addr:     cfg.Addr(),
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
+func NewTaskProducer(cfg config.RedisConfig, workerCfg config.WorkerConfig) *TaskProducer {
+	rdb := newRedisClient(cfg)
 
-	return &TaskProducer{client: client}
+	return &TaskProducer{
+		redis:     rdb,
+		client:    asynq.NewClientFromRedisClient(rdb),
+		inspector: asynq.NewInspectorFromRedisClient(rdb),
+		cfg:       workerCfg,
+	}
 }
 
 // Enqueue добавляет задачу в очередь
 func (p *TaskProducer) Enqueue(ctx context.Context, taskID uuid.UUID) error {
+	ctx, span := tracing.Tracer().Start(ctx, "queue.Enqueue",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "asynq"),
+			attribute.String("messaging.destination.name", p.cfg.Queue),
+			attribute.String("task_id", taskID.String()),
+		),
+	)
+	defer span.End()
+
 	payload, err := json.Marshal(DocumentRecognitionPayload{
-		TaskID: taskID.String(),
+		TaskID:       taskID.String(),
+		RequestID:    middleware.GetReqID(ctx),
+		TraceContext: tracing.Inject(ctx),
 	})
 	if err != nil {
+		tracing.RecordError(span, err)
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	task := asynq.NewTask(TypeDocumentRecognition, payload,
-		asynq.MaxRetry(3),              // Максимум 3 попытки
-		asynq.Queue("recognition"),     // Очередь для распознавания
-	)
+	opts := []asynq.Option{
+		asynq.MaxRetry(p.cfg.MaxRetry), // Максимальное количество повторов
+		asynq.Queue(p.cfg.Queue),       // Очередь для распознавания
+		asynq.TaskID(taskID.String()),  // Повторная постановка не создаёт дубликат
+	}
+	if p.cfg.TaskTimeout > 0 {
+		opts = append(opts, asynq.Timeout(p.cfg.TaskTimeout))
+	}
+	if p.cfg.TaskDeadline > 0 {
+		opts = append(opts, asynq.Deadline(time.Now().Add(p.cfg.TaskDeadline)))
+	}
+
+	task := asynq.NewTask(TypeDocumentRecognition, payload, opts...)
 
 	_, err = p.client.EnqueueContext(ctx, task)
+	if errors.Is(err, asynq.ErrTaskIDConflict) {
+		if err := p.checkConflict(taskID); err != nil {
+			tracing.RecordError(span, err)
+			return err
+		}
+		// Задача уже в очереди и будет обработана
+		span.AddEvent("task already enqueued")
+		return nil
+	}
 	if err != nil {
+		tracing.RecordError(span, err)
 		return fmt.Errorf("failed to enqueue task: %w", err)
 	}
 
 	return nil
 }
 
+// checkConflict проверяет задачу с тем же ID, уже находящуюся в очереди.
+// Повторная постановка считается успешной, только если задача ещё будет обработана;
+// завершённая или архивированная запись не выполнится, и об этом нужно сообщить вызывающему
+func (p *TaskProducer) checkConflict(taskID uuid.UUID) error {
+	info, err := p.inspector.GetTaskInfo(p.cfg.Queue, taskID.String())
+	if err != nil {
+		return fmt.Errorf("failed to inspect conflicting task: %w", err)
+	}
+
+	switch info.State {
+	case asynq.TaskStatePending, asynq.TaskStateScheduled, asynq.TaskStateRetry, asynq.TaskStateActive:
+		return nil
+	default:
+		return fmt.Errorf("task %s is already in queue in state %s: %w", taskID, info.State, asynq.ErrTaskIDConflict)
+	}
+}
+
+// CheckHealth проверяет доступность Redis
+func (p *TaskProducer) CheckHealth(ctx context.Context) error {
+	if err := p.redis.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to ping redis: %w", err)
+	}
+	return nil
+}
+
 // Close закрывает соединение
 func (p *TaskProducer) Close() error {
-	return p.client.Close()
+	// Общее соединение закрывается напрямую, asynq.Client и asynq.Inspector его не закрывают
+	return p.redis.Close()
+}
+
+// newRedisClient создаёт соединение с Redis для клиента и сервера asynq
+func newRedisClient(cfg config.RedisConfig) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr(),
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
 }
\ No newline at end of file
@@ -0,0 +1,39 @@
+package queue
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/plastinin/docrecognizer/internal/config"
+)
+
+// newRetryDelayFunc создаёт функцию экспоненциальной задержки между попытками со случайным разбросом
+func newRetryDelayFunc(cfg config.WorkerConfig) asynq.RetryDelayFunc {
+	return func(n int, _ error, _ *asynq.Task) time.Duration {
+		return retryDelay(n, cfg.RetryBaseDelay, cfg.RetryMaxDelay, cfg.RetryJitter)
+	}
+}
+
+// retryDelay вычисляет задержку перед n-й повторной попыткой: base * 2^n, но не больше max
+func retryDelay(n int, base, max time.Duration, jitter float64) time.Duration {
+	if base <= 0 {
+		return asynq.DefaultRetryDelayFunc(n, nil, nil)
+	}
+
+	delay := float64(base) * math.Pow(2, float64(n))
+	if max > 0 && delay > float64(max) {
+		delay = float64(max)
+	}
+
+	// Разброс в диапазоне [-jitter; +jitter], чтобы повторы не приходили одновременно
+	if jitter > 0 {
+		delay += delay * jitter * (rand.Float64()*2 - 1)
+	}
+
+	if delay < 0 {
+		return 0
+	}
+	return time.Duration(delay)
+}
@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// migrationLockKey ключ advisory lock, чтобы реплики не применяли миграции одновременно
+const migrationLockKey int64 = 0x646f637265636f67 // "docrecog"
+
+var (
+	ErrDirtyDatabase = errors.New("database is dirty")
+
+	migrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+)
+
+// Migration версия схемы БД
+type Migration struct {
+	Version int64
+	Name    string
+	up      string
+	down    string
+}
+
+// MigrationStatus состояние миграций
+type MigrationStatus struct {
+	Version    int64 // Текущая версия схемы (0 — миграции не применялись)
+	Dirty      bool
+	Migrations []MigrationState
+}
+
+// MigrationState состояние отдельной миграции
+type MigrationState struct {
+	Version int64
+	Name    string
+	Applied bool
+}
+
+// Migrator применяет SQL миграции. Таблица версий совместима с golang-migrate,
+// поэтому базы, мигрированные внешним CLI, продолжают работать
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// NewMigrator создаёт новый экземпляр Migrator из файлов миграций
+func NewMigrator(pool *pgxpool.Pool, fsys fs.FS, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		pool:       pool,
+		migrations: migrations,
+		logger:     logger,
+	}, nil
+}
+
+// loadMigrations читает и сортирует миграции по версии
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if match[3] == "up" {
+			m.up = string(body)
+		} else {
+			m.down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up применяет все неприменённые миграции и возвращает их количество
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, err := m.currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if migration.Version <= version {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration.up, migration.Version); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			m.logger.Info("Migration applied",
+				zap.Int64("version", migration.Version),
+				zap.String("name", migration.Name),
+			)
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down откатывает steps последних миграций и возвращает количество откаченных
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	reverted := 0
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		version, err := m.currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := m.migrations[i]
+			if migration.Version > version {
+				continue
+			}
+			if migration.down == "" {
+				return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+			}
+
+			// Версия после отката — предыдущая миграция (0, если её нет)
+			var prev int64
+			if i > 0 {
+				prev = m.migrations[i-1].Version
+			}
+
+			if err := m.apply(ctx, conn, migration.down, prev); err != nil {
+				return fmt.Errorf("failed to revert migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+			m.logger.Info("Migration reverted",
+				zap.Int64("version", migration.Version),
+				zap.String("name", migration.Name),
+			)
+			version = prev
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status возвращает состояние миграций
+func (m *Migrator) Status(ctx context.Context) (*MigrationStatus, error) {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if err := m.ensureVersionTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	version, dirty, err := m.readVersion(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MigrationStatus{
+		Version:    version,
+		Dirty:      dirty,
+		Migrations: make([]MigrationState, 0, len(m.migrations)),
+	}
+	for _, migration := range m.migrations {
+		status.Migrations = append(status.Migrations, MigrationState{
+			Version: migration.Version,
+			Name:    migration.Name,
+			Applied: migration.Version <= version,
+		})
+	}
+
+	return status, nil
+}
+
+// withLock выполняет fn на выделенном соединении под advisory lock
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Снимаем блокировку даже при отменённом контексте
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			m.logger.Error("Failed to release migration lock", zap.Error(err))
+		}
+	}()
+
+	if err := m.ensureVersionTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// ensureVersionTable создаёт таблицу версий в формате golang-migrate
+func (m *Migrator) ensureVersionTable(ctx context.Context, conn *pgxpool.Conn) error {
+	_, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// currentVersion возвращает текущую версию схемы, отказываясь работать с «грязной» БД
+func (m *Migrator) currentVersion(ctx context.Context, conn *pgxpool.Conn) (int64, error) {
+	version, dirty, err := m.readVersion(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d, fix the schema manually and reset the dirty flag", ErrDirtyDatabase, version)
+	}
+	return version, nil
+}
+
+// readVersion читает версию схемы из schema_migrations
+func (m *Migrator) readVersion(ctx context.Context, conn *pgxpool.Conn) (int64, bool, error) {
+	var version int64
+	var dirty bool
+
+	err := conn.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+// apply выполняет SQL миграции и записывает новую версию в одной транзакции
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, sql string, version int64) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return fmt.Errorf("failed to reset schema version: %w", err)
+	}
+	if version > 0 {
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)`, version); err != nil {
+			return fmt.Errorf("failed to set schema version: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+)
+
+// PromptRepository промпты модели, хранящиеся в БД
+type PromptRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewPromptRepository создаёт новый экземпляр PromptRepository
+func NewPromptRepository(pool *pgxpool.Pool) *PromptRepository {
+	return &PromptRepository{pool: pool}
+}
+
+// List возвращает все промпты
+func (r *PromptRepository) List(ctx context.Context) ([]domain.PromptTemplate, error) {
+	ctx, span := startSpan(ctx, "ListPrompts")
+	defer span.End()
+
+	rows, err := r.pool.Query(ctx, `SELECT name, body FROM prompt_templates ORDER BY name`)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to query prompt templates: %w", err)
+	}
+	defer rows.Close()
+
+	var prompts []domain.PromptTemplate
+	for rows.Next() {
+		var prompt domain.PromptTemplate
+		if err := rows.Scan(&prompt.Name, &prompt.Body); err != nil {
+			tracing.RecordError(span, err)
+			return nil, fmt.Errorf("failed to scan prompt template: %w", err)
+		}
+		prompts = append(prompts, prompt)
+	}
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return prompts, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+)
+
+// RetentionRepository операции политик хранения над таблицей задач
+type RetentionRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewRetentionRepository создаёт новый экземпляр RetentionRepository
+func NewRetentionRepository(pool *pgxpool.Pool) *RetentionRepository {
+	return &RetentionRepository{pool: pool}
+}
+
+// retentionPendingConditions условия, при которых действие ещё не выполнено
+var retentionPendingConditions = map[domain.RetentionAction]string{
+	domain.RetentionActionDeleteFile:  "file_deleted_at IS NULL",
+	domain.RetentionActionPurgeResult: "(file_deleted_at IS NULL OR result_purged_at IS NULL)",
+	domain.RetentionActionDelete:      "TRUE",
+}
+
+// ListExpired возвращает задачи, попадающие под политику и завершённые раньше before
+func (r *RetentionRepository) ListExpired(ctx context.Context, policy domain.RetentionPolicy, before time.Time, limit int) ([]*domain.Task, error) {
+	ctx, span := startSpan(ctx, "ListExpired")
+	defer span.End()
+
+	condition, ok := retentionPendingConditions[policy.Action]
+	if !ok {
+		return nil, domain.ErrInvalidRetentionPolicy
+	}
+
+	args := []any{policy.Status, before, limit}
+	switch {
+	case policy.Tenant != "":
+		args = append(args, policy.Tenant)
+		condition += " AND tenant = $4"
+	case len(policy.ExcludeTenants) > 0:
+		// У этих арендаторов собственные политики для статуса
+		args = append(args, policy.ExcludeTenants)
+		condition += " AND (tenant IS NULL OR tenant <> ALL($4))"
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM tasks
+		WHERE status = $1 AND COALESCE(completed_at, created_at) < $2 AND ` + condition + `
+		ORDER BY COALESCE(completed_at, created_at)
+		LIMIT $3`
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to query expired tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]*domain.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return tasks, nil
+}
+
+// FileKeyInUse проверяет, ссылаются ли на файл другие задачи, чей файл ещё не удалён
+func (r *RetentionRepository) FileKeyInUse(ctx context.Context, fileKey string, exceptID uuid.UUID) (bool, error) {
+	ctx, span := startSpan(ctx, "FileKeyInUse")
+	defer span.End()
+
+	inUse, err := fileKeyInUse(ctx, r.pool, fileKey, exceptID)
+	tracing.RecordError(span, err)
+	return inUse, err
+}
+
+// Apply выполняет действие политики над задачей и пишет запись в журнал.
+// Возвращает false, если действие уже выполнено (например, другой репликой)
+func (r *RetentionRepository) Apply(ctx context.Context, entry *domain.RetentionAuditEntry) (bool, error) {
+	ctx, span := startSpan(ctx, "ApplyRetention")
+	defer span.End()
+
+	query := ""
+	args := []any{entry.TaskID, entry.ExecutedAt}
+	switch entry.Action {
+	case domain.RetentionActionDeleteFile:
+		query = `UPDATE tasks SET file_deleted_at = $2 WHERE id = $1 AND file_deleted_at IS NULL`
+	case domain.RetentionActionPurgeResult:
+		query = `UPDATE tasks
+			SET result = NULL,
+				file_deleted_at = COALESCE(file_deleted_at, $2),
+				result_purged_at = $2
+			WHERE id = $1 AND (file_deleted_at IS NULL OR result_purged_at IS NULL)`
+	case domain.RetentionActionDelete:
+		query = `DELETE FROM tasks WHERE id = $1`
+		args = args[:1]
+	default:
+		return false, domain.ErrInvalidRetentionPolicy
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return false, fmt.Errorf("failed to apply retention action: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO retention_audit (task_id, action, policy, file_key, task_status, task_created_at, executed_at, tenant)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`,
+		entry.TaskID,
+		entry.Action,
+		entry.Policy,
+		entry.FileKey,
+		entry.TaskStatus,
+		entry.TaskCreatedAt,
+		entry.ExecutedAt,
+		nullString(entry.Tenant),
+	)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return false, fmt.Errorf("failed to insert retention audit entry: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		tracing.RecordError(span, err)
+		return false, fmt.Errorf("failed to commit retention action: %w", err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/plastinin/docrecognizer/internal/domain"
+)
+
+// taskColumns колонки таблицы tasks в порядке сканирования scanTask
+const taskColumns = `id, status, file_key, file_name, tenant, content_type, schema, template, render_options, input_mode, batch_id, split_mode, parent_id,
+	file_hash, duplicate_of, cache_bypass, cache_status, source_url, page_keys, result, error, error_code, password, classify, document_type, classification_confidence, prompt_version, created_at, updated_at, completed_at, file_deleted_at, result_purged_at`
+
+// scanTask сканирует строку с колонками taskColumns
+func scanTask(row pgx.Row) (*domain.Task, error) {
+	task := &domain.Task{}
+	var errorMsg *string // Указатель для NULL
+	var errorCode *string
+	var template *string
+	var tenant *string
+	var fileHash *string
+	var cacheStatus *string
+	var sourceURL *string
+	var render *domain.RenderOptions
+	var inputMode *string
+	var documentType *string
+	var confidence *float64
+	var split *string
+	var promptVersion *string
+
+	err := row.Scan(
+		&task.ID,
+		&task.Status,
+		&task.FileKey,
+		&task.FileName,
+		&tenant,
+		&task.ContentType,
+		&task.Schema,
+		&template,
+		&render,
+		&inputMode,
+		&task.BatchID,
+		&split,
+		&task.ParentID,
+		&fileHash,
+		&task.DuplicateOf,
+		&task.CacheBypass,
+		&cacheStatus,
+		&sourceURL,
+		&task.PageKeys,
+		&task.Result,
+		&errorMsg,
+		&errorCode,
+		&task.Password,
+		&task.Classify,
+		&documentType,
+		&confidence,
+		&promptVersion,
+		&task.CreatedAt,
+		&task.UpdatedAt,
+		&task.CompletedAt,
+		&task.FileDeletedAt,
+		&task.ResultPurgedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Обрабатываем NULL
+	if errorMsg != nil {
+		task.Error = *errorMsg
+	}
+	if errorCode != nil {
+		task.ErrorCode = *errorCode
+	}
+	if template != nil {
+		task.Template = *template
+	}
+	if tenant != nil {
+		task.Tenant = *tenant
+	}
+	if fileHash != nil {
+		task.FileHash = *fileHash
+	}
+	if cacheStatus != nil {
+		task.CacheStatus = domain.CacheStatus(*cacheStatus)
+	}
+	if sourceURL != nil {
+		task.SourceURL = *sourceURL
+	}
+	if render != nil {
+		task.Render = *render
+	}
+	if inputMode != nil {
+		task.InputMode = domain.InputMode(*inputMode)
+	}
+	if split != nil {
+		task.Split = domain.SplitMode(*split)
+	}
+	if documentType != nil {
+		task.DocumentType = *documentType
+	}
+	if confidence != nil {
+		task.ClassificationConfidence = *confidence
+	}
+	if promptVersion != nil {
+		task.PromptVersion = *promptVersion
+	}
+
+	return task, nil
+}
+
+// nullString возвращает nil для пустой строки
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// nonNilStrings возвращает пустой срез вместо nil для колонок-массивов NOT NULL
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// nullConfidence возвращает nil, если задача не классифицирована (NULL в БД)
+func nullConfidence(task *domain.Task) *float64 {
+	if task.DocumentType == "" {
+		return nil
+	}
+	return &task.ClassificationConfidence
+}
+
+// nullRenderOptions возвращает nil для незаданных параметров рендеринга (NULL в БД)
+func nullRenderOptions(o domain.RenderOptions) *domain.RenderOptions {
+	if o.IsZero() {
+		return nil
+	}
+	return &o
+}
+
+// sortColumn SQL выражение и тип для поля сортировки
+type sortColumn struct {
+	expr     string
+	cast     string
+	valueFor func(task *domain.Task) string
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// sortColumns поддерживаемые поля сортировки
+var sortColumns = map[domain.TaskSortField]sortColumn{
+	domain.TaskSortByCreatedAt: {
+		expr:     "created_at",
+		cast:     "timestamptz",
+		valueFor: func(t *domain.Task) string { return formatTime(t.CreatedAt) },
+	},
+	domain.TaskSortByUpdatedAt: {
+		expr:     "updated_at",
+		cast:     "timestamptz",
+		valueFor: func(t *domain.Task) string { return formatTime(t.UpdatedAt) },
+	},
+	domain.TaskSortByCompletedAt: {
+		// Незавершённые задачи считаем самыми старыми, чтобы курсор оставался однозначным
+		expr: "COALESCE(completed_at, 'epoch'::timestamptz)",
+		cast: "timestamptz",
+		valueFor: func(t *domain.Task) string {
+			if t.CompletedAt == nil {
+				return formatTime(time.Unix(0, 0))
+			}
+			return formatTime(*t.CompletedAt)
+		},
+	},
+	domain.TaskSortByFileName: {
+		expr:     "file_name",
+		cast:     "text",
+		valueFor: func(t *domain.Task) string { return t.FileName },
+	},
+	domain.TaskSortByStatus: {
+		expr:     "status",
+		cast:     "task_status",
+		valueFor: func(t *domain.Task) string { return t.Status.String() },
+	},
+}
+
+// cursorFor возвращает курсор, указывающий на задачу
+func cursorFor(task *domain.Task, field domain.TaskSortField) domain.Cursor {
+	return domain.Cursor{
+		Value: sortColumns[field].valueFor(task),
+		ID:    task.ID,
+	}
+}
+
+// taskQueryBuilder собирает WHERE условия и аргументы запроса списка задач
+type taskQueryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+func newTaskQueryBuilder() *taskQueryBuilder {
+	return &taskQueryBuilder{conditions: []string{"1=1"}}
+}
+
+// add добавляет аргумент и возвращает его плейсхолдер
+func (b *taskQueryBuilder) add(arg any) string {
+	b.args = append(b.args, arg)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// addCondition добавляет условие; %s в format заменяются плейсхолдерами args
+func (b *taskQueryBuilder) addCondition(format string, args ...any) {
+	placeholders := make([]any, len(args))
+	for i, arg := range args {
+		placeholders[i] = b.add(arg)
+	}
+	b.conditions = append(b.conditions, fmt.Sprintf(format, placeholders...))
+}
+
+func (b *taskQueryBuilder) where() string {
+	return strings.Join(b.conditions, " AND ")
+}
+
+// applyFilter добавляет условия фильтра
+func (b *taskQueryBuilder) applyFilter(filter domain.TaskFilter) {
+	if filter.Status != nil {
+		b.addCondition("status = %s", *filter.Status)
+	}
+	if filter.CreatedFrom != nil {
+		b.addCondition("created_at >= %s", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		b.addCondition("created_at < %s", *filter.CreatedTo)
+	}
+	if filter.CompletedFrom != nil {
+		b.addCondition("completed_at >= %s", *filter.CompletedFrom)
+	}
+	if filter.CompletedTo != nil {
+		b.addCondition("completed_at < %s", *filter.CompletedTo)
+	}
+	if filter.FileName != "" {
+		b.addCondition(`file_name ILIKE %s ESCAPE '\'`, "%"+escapeLike(filter.FileName)+"%")
+	}
+	if filter.ContentType != "" {
+		b.addCondition("content_type = %s", filter.ContentType)
+	}
+	if filter.Template != "" {
+		b.addCondition("template = %s", filter.Template)
+	}
+	if filter.Tenant != "" {
+		b.addCondition("tenant = %s", filter.Tenant)
+	}
+	if filter.BatchID != nil {
+		b.addCondition("batch_id = %s", *filter.BatchID)
+	}
+	if filter.ParentID != nil {
+		b.addCondition("parent_id = %s", *filter.ParentID)
+	}
+	if filter.FileHash != "" {
+		b.addCondition("file_hash = %s", filter.FileHash)
+	}
+	if filter.Search != "" {
+		b.addCondition("to_tsvector('simple', COALESCE(result::text, '')) @@ plainto_tsquery('simple', %s)", filter.Search)
+	}
+	for field, value := range filter.ResultFields {
+		b.addResultFieldCondition(field, value)
+	}
+}
+
+// addResultFieldCondition добавляет условие на поле результата. Вхождение (@>) использует
+// GIN индекс idx_tasks_result; значение, похожее на число или bool, ищется и как строка, и как JSON
+func (b *taskQueryBuilder) addResultFieldCondition(field, value string) {
+	var scalar any
+	if err := json.Unmarshal([]byte(value), &scalar); err == nil {
+		switch scalar.(type) {
+		case float64, bool:
+			b.addCondition("(result @> jsonb_build_object(%s::text, %s::text) OR result @> jsonb_build_object(%s::text, %s::jsonb))",
+				field, value, field, value)
+			return
+		}
+	}
+	b.addCondition("result @> jsonb_build_object(%s::text, %s::text)", field, value)
+}
+
+// applyCursor добавляет условие keyset пагинации
+func (b *taskQueryBuilder) applyCursor(sort domain.TaskSort, cursor *domain.Cursor) error {
+	column := sortColumns[sort.Field]
+
+	// Проверяем значение курсора заранее, чтобы не получить ошибку приведения типа от БД
+	if column.cast == "timestamptz" {
+		if _, err := time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+			return domain.ErrInvalidCursor
+		}
+	}
+	if column.cast == "task_status" && !domain.TaskStatus(cursor.Value).IsValid() {
+		return domain.ErrInvalidCursor
+	}
+
+	op := ">"
+	if sort.Desc {
+		op = "<"
+	}
+	b.conditions = append(b.conditions, fmt.Sprintf("(%s, id) %s (%s::%s, %s)",
+		column.expr, op, b.add(cursor.Value), column.cast, b.add(cursor.ID)))
+
+	return nil
+}
+
+// orderBy возвращает ORDER BY для сортировки; id обеспечивает стабильный порядок
+func (b *taskQueryBuilder) orderBy(sort domain.TaskSort) string {
+	dir := "ASC"
+	if sort.Desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("%s %s, id %s", sortColumns[sort.Field].expr, dir, dir)
+}
+
+// escapeLike экранирует спецсимволы LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/plastinin/docrecognizer/internal/domain"
+)
+
+func TestApplyCursor(t *testing.T) {
+	id := uuid.New()
+	createdAt := formatTime(time.Date(2024, 5, 1, 10, 0, 0, 123456000, time.UTC))
+
+	tests := []struct {
+		name   string
+		sort   domain.TaskSort
+		cursor domain.Cursor
+		want   string
+	}{
+		{
+			"created desc",
+			domain.TaskSort{Field: domain.TaskSortByCreatedAt, Desc: true},
+			domain.Cursor{Value: createdAt, ID: id},
+			"(created_at, id) < ($1::timestamptz, $2)",
+		},
+		{
+			"created asc",
+			domain.TaskSort{Field: domain.TaskSortByCreatedAt},
+			domain.Cursor{Value: createdAt, ID: id},
+			"(created_at, id) > ($1::timestamptz, $2)",
+		},
+		{
+			"completed",
+			domain.TaskSort{Field: domain.TaskSortByCompletedAt, Desc: true},
+			domain.Cursor{Value: formatTime(time.Unix(0, 0)), ID: id},
+			"(COALESCE(completed_at, 'epoch'::timestamptz), id) < ($1::timestamptz, $2)",
+		},
+		{
+			"file name",
+			domain.TaskSort{Field: domain.TaskSortByFileName},
+			domain.Cursor{Value: "a.pdf", ID: id},
+			"(file_name, id) > ($1::text, $2)",
+		},
+		{
+			"status",
+			domain.TaskSort{Field: domain.TaskSortByStatus},
+			domain.Cursor{Value: domain.TaskStatusCompleted.String(), ID: id},
+			"(status, id) > ($1::task_status, $2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &taskQueryBuilder{}
+			if err := b.applyCursor(tt.sort, &tt.cursor); err != nil {
+				t.Fatalf("applyCursor: %v", err)
+			}
+			if b.where() != tt.want {
+				t.Errorf("condition = %q, want %q", b.where(), tt.want)
+			}
+			if want := []any{tt.cursor.Value, id}; !reflect.DeepEqual(b.args, want) {
+				t.Errorf("args = %v, want %v", b.args, want)
+			}
+		})
+	}
+}
+
+func TestApplyCursorInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		field domain.TaskSortField
+		value string
+	}{
+		{"not a timestamp", domain.TaskSortByCreatedAt, "yesterday"},
+		{"date without time", domain.TaskSortByUpdatedAt, "2024-05-01"},
+		{"empty timestamp", domain.TaskSortByCompletedAt, ""},
+		{"unknown status", domain.TaskSortByStatus, "archived"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &taskQueryBuilder{}
+			err := b.applyCursor(domain.TaskSort{Field: tt.field}, &domain.Cursor{Value: tt.value, ID: uuid.New()})
+			if !errors.Is(err, domain.ErrInvalidCursor) {
+				t.Errorf("error = %v, want ErrInvalidCursor", err)
+			}
+			if len(b.conditions) != 0 || len(b.args) != 0 {
+				t.Errorf("invalid cursor added condition %v with args %v", b.conditions, b.args)
+			}
+		})
+	}
+}
+
+func TestCursorForMatchesApplyCursor(t *testing.T) {
+	completedAt := time.Now()
+	task := &domain.Task{
+		ID:          uuid.New(),
+		Status:      domain.TaskStatusCompleted,
+		FileName:    "file.pdf",
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+		CompletedAt: &completedAt,
+	}
+
+	// Курсор, построенный по задаче, всегда принимается applyCursor
+	for field := range sortColumns {
+		cursor := cursorFor(task, field)
+		b := &taskQueryBuilder{}
+		if err := b.applyCursor(domain.TaskSort{Field: field}, &cursor); err != nil {
+			t.Errorf("%s: applyCursor(cursorFor()) = %v", field, err)
+		}
+	}
+
+	// Незавершённая задача тоже получает корректный курсор
+	task.CompletedAt = nil
+	cursor := cursorFor(task, domain.TaskSortByCompletedAt)
+	if err := (&taskQueryBuilder{}).applyCursor(domain.TaskSort{Field: domain.TaskSortByCompletedAt}, &cursor); err != nil {
+		t.Errorf("applyCursor for pending task cursor = %v", err)
+	}
+}
+
+func TestResultFieldCondition(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"string", "ООО Ромашка", "result @> jsonb_build_object($1::text, $2::text)"},
+		{"number", "1500.5", "(result @> jsonb_build_object($1::text, $2::text) OR result @> jsonb_build_object($3::text, $4::jsonb))"},
+		{"bool", "true", "(result @> jsonb_build_object($1::text, $2::text) OR result @> jsonb_build_object($3::text, $4::jsonb))"},
+		{"json string stays text", `"quoted"`, "result @> jsonb_build_object($1::text, $2::text)"},
+		{"json object stays text", `{"a":1}`, "result @> jsonb_build_object($1::text, $2::text)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &taskQueryBuilder{}
+			b.applyFilter(domain.TaskFilter{ResultFields: map[string]string{"supplier": tt.value}})
+			if b.where() != tt.want {
+				t.Errorf("condition = %q, want %q", b.where(), tt.want)
+			}
+			for i, arg := range b.args {
+				want := "supplier"
+				if i%2 == 1 {
+					want = tt.value
+				}
+				if arg != want {
+					t.Errorf("arg $%d = %v, want %q", i+1, arg, want)
+				}
+			}
+		})
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // TaskRepository реализация репозитория задач для PostgreSQL
@@ -21,74 +24,254 @@ func NewTaskRepository(pool *pgxpool.Pool) *TaskRepository {
 	return &TaskRepository{pool: pool}
 }
 
-// Create создаёт новую задачу в БД
-func (r *TaskRepository) Create(ctx context.Context, task *domain.Task) error {
-	query := `
-		INSERT INTO tasks (id, status, file_key, file_name, content_type, schema, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-	`
-
-	_, err := r.pool.Exec(ctx, query,
+// insertTaskQuery запрос вставки задачи с аргументами insertTaskArgs
+const insertTaskQuery = `
+	INSERT INTO tasks (id, status, file_key, file_name, content_type, schema, template, render_options, input_mode, batch_id,
+		file_hash, duplicate_of, cache_bypass, source_url, result, created_at, updated_at, completed_at, password, classify,
+		split_mode, parent_id, tenant)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23)
+`
+
+// insertTaskArgs возвращает аргументы insertTaskQuery
+func insertTaskArgs(task *domain.Task) []any {
+	return []any{
 		task.ID,
 		task.Status,
 		task.FileKey,
 		task.FileName,
 		task.ContentType,
-		task.Schema,
+		nonNilStrings(task.Schema),
+		nullString(task.Template),
+		nullRenderOptions(task.Render),
+		nullString(task.InputMode.String()),
+		task.BatchID,
+		nullString(task.FileHash),
+		task.DuplicateOf,
+		task.CacheBypass,
+		nullString(task.SourceURL),
+		task.Result,
 		task.CreatedAt,
 		task.UpdatedAt,
-	)
+		task.CompletedAt,
+		task.Password,
+		task.Classify,
+		nullString(task.Split.String()),
+		task.ParentID,
+		nullString(task.Tenant),
+	}
+}
+
+// Create создаёт новую задачу в БД
+func (r *TaskRepository) Create(ctx context.Context, task *domain.Task) error {
+	ctx, span := startSpan(ctx, "Create")
+	defer span.End()
+
+	if _, err := r.pool.Exec(ctx, insertTaskQuery, insertTaskArgs(task)...); err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("failed to insert task: %w", err)
+	}
+
+	return nil
+}
+
+// CreateWithFreeFile создаёт задачу, если на её файл не ссылается ни одна другая задача.
+// Проверка и вставка выполняются под блокировкой ключа файла, поэтому одновременные
+// запросы с одним ключом не создадут две задачи. Возвращает domain.ErrFileKeyInUse
+func (r *TaskRepository) CreateWithFreeFile(ctx context.Context, task *domain.Task) error {
+	ctx, span := startSpan(ctx, "CreateWithFreeFile")
+	defer span.End()
+
+	tx, err := r.pool.Begin(ctx)
 	if err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, task.FileKey); err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("failed to lock file key: %w", err)
+	}
+
+	inUse, err := fileKeyInUse(ctx, tx, task.FileKey, task.ID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return err
+	}
+	if inUse {
+		return domain.ErrFileKeyInUse
+	}
+
+	if _, err := tx.Exec(ctx, insertTaskQuery, insertTaskArgs(task)...); err != nil {
+		tracing.RecordError(span, err)
 		return fmt.Errorf("failed to insert task: %w", err)
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("failed to commit task: %w", err)
+	}
+
 	return nil
 }
 
+// CreateChildren атомарно создаёт задачи документов, выделенных из файла родительской задачи
+func (r *TaskRepository) CreateChildren(ctx context.Context, children []*domain.Task) error {
+	ctx, span := startSpan(ctx, "CreateChildren")
+	defer span.End()
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, child := range children {
+		if _, err := tx.Exec(ctx, insertTaskQuery, insertTaskArgs(child)...); err != nil {
+			tracing.RecordError(span, err)
+			return fmt.Errorf("failed to insert child task: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("failed to commit child tasks: %w", err)
+	}
+
+	return nil
+}
+
+// ListChildren возвращает задачи документов, выделенных из файла задачи, по порядку страниц
+func (r *TaskRepository) ListChildren(ctx context.Context, parentID uuid.UUID) ([]*domain.Task, error) {
+	ctx, span := startSpan(ctx, "ListChildren")
+	defer span.End()
+
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE parent_id = $1 ORDER BY created_at, id`
+
+	rows, err := r.pool.Query(ctx, query, parentID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to query child tasks: %w", err)
+	}
+	defer rows.Close()
+
+	children := make([]*domain.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		children = append(children, task)
+	}
+
+	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("rows iteration error: %w", err)
+	}
+
+	return children, nil
+}
+
+// FileKeyInUse проверяет, ссылаются ли на файл другие задачи, чей файл ещё не удалён.
+// Файл разделённого документа общий для родительской и дочерних задач
+func (r *TaskRepository) FileKeyInUse(ctx context.Context, fileKey string, exceptID uuid.UUID) (bool, error) {
+	ctx, span := startSpan(ctx, "FileKeyInUse")
+	defer span.End()
+
+	inUse, err := fileKeyInUse(ctx, r.pool, fileKey, exceptID)
+	tracing.RecordError(span, err)
+	return inUse, err
+}
+
+// rowQuerier выполняет запрос с одной строкой результата: пул соединений или транзакция
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// fileKeyInUse проверяет ссылки других задач на файл
+func fileKeyInUse(ctx context.Context, db rowQuerier, fileKey string, exceptID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (
+		SELECT 1 FROM tasks WHERE file_key = $1 AND id <> $2 AND file_deleted_at IS NULL
+	)`
+
+	var inUse bool
+	if err := db.QueryRow(ctx, query, fileKey, exceptID).Scan(&inUse); err != nil {
+		return false, fmt.Errorf("failed to check file references: %w", err)
+	}
+	return inUse, nil
+}
+
 // GetByID возвращает задачу по ID
 func (r *TaskRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Task, error) {
-	query := `
-		SELECT id, status, file_key, file_name, content_type, schema, result, error, created_at, updated_at, completed_at
-		FROM tasks
-		WHERE id = $1
-	`
+	ctx, span := startSpan(ctx, "GetByID")
+	defer span.End()
 
-	task := &domain.Task{}
-	var errorMsg *string  // Указатель для NULL
-
-	err := r.pool.QueryRow(ctx, query, id).Scan(
-		&task.ID,
-		&task.Status,
-		&task.FileKey,
-		&task.FileName,
-		&task.ContentType,
-		&task.Schema,
-		&task.Result,
-		&errorMsg,  // Сканируем в указатель
-		&task.CreatedAt,
-		&task.UpdatedAt,
-		&task.CompletedAt,
-	)
+	query := `SELECT ` + taskColumns + ` FROM tasks WHERE id = $1`
+
+	task, err := scanTask(r.pool.QueryRow(ctx, query, id))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, domain.ErrTaskNotFound
 		}
+		tracing.RecordError(span, err)
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
-	// Обрабатываем NULL
-	if errorMsg != nil {
-		task.Error = *errorMsg
+	return task, nil
+}
+
+// FindCompletedByHash возвращает последнюю завершённую задачу с тем же содержимым файла
+// и теми же параметрами, от которых зависит результат: схема, шаблон, рендеринг PDF,
+// входные данные модели и классификация. Результат ищется только среди задач того же арендатора.
+// Новый параметр задачи, влияющий на результат, нужно добавить в это условие
+func (r *TaskRepository) FindCompletedByHash(ctx context.Context, task *domain.Task) (*domain.Task, error) {
+	ctx, span := startSpan(ctx, "FindCompletedByHash")
+	defer span.End()
+
+	// Схемы сравниваются как множества: порядок полей не важен
+	query := `SELECT ` + taskColumns + ` FROM tasks
+		WHERE file_hash = $1 AND status = 'completed' AND result_purged_at IS NULL AND split_mode IS NULL
+			AND schema @> $2 AND schema <@ $2
+			AND template IS NOT DISTINCT FROM $3
+			AND render_options IS NOT DISTINCT FROM $4
+			AND input_mode IS NOT DISTINCT FROM $5
+			AND classify = $6
+			AND tenant IS NOT DISTINCT FROM $7
+		ORDER BY completed_at DESC
+		LIMIT 1`
+
+	found, err := scanTask(r.pool.QueryRow(ctx, query,
+		task.FileHash,
+		nonNilStrings(task.Schema),
+		nullString(task.Template),
+		nullRenderOptions(task.Render),
+		nullString(task.InputMode.String()),
+		task.Classify,
+		nullString(task.Tenant),
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, domain.ErrTaskNotFound
+		}
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to find task by hash: %w", err)
 	}
 
-	return task, nil
+	return found, nil
 }
 
 // Update обновляет задачу в БД
 func (r *TaskRepository) Update(ctx context.Context, task *domain.Task) error {
+	ctx, span := startSpan(ctx, "Update")
+	defer span.End()
+
 	query := `
 		UPDATE tasks
-		SET status = $2, result = $3, error = $4, updated_at = $5, completed_at = $6
+		SET status = $2, result = $3, error = $4, updated_at = $5, completed_at = $6, cache_status = $7,
+			file_key = $8, content_type = $9, file_hash = $10, page_keys = $11, error_code = $12, password = $13,
+			schema = $14, document_type = $15, classification_confidence = $16, prompt_version = $17
 		WHERE id = $1
 	`
 
@@ -99,8 +282,20 @@ func (r *TaskRepository) Update(ctx context.Context, task *domain.Task) error {
 		task.Error,
 		task.UpdatedAt,
 		task.CompletedAt,
+		nullString(task.CacheStatus.String()),
+		task.FileKey,
+		task.ContentType,
+		nullString(task.FileHash),
+		task.PageKeys,
+		nullString(task.ErrorCode),
+		task.Password,
+		nonNilStrings(task.Schema),
+		nullString(task.DocumentType),
+		nullConfidence(task),
+		nullString(task.PromptVersion),
 	)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return fmt.Errorf("failed to update task: %w", err)
 	}
 
@@ -113,10 +308,14 @@ func (r *TaskRepository) Update(ctx context.Context, task *domain.Task) error {
 
 // Delete удаляет задачу из БД
 func (r *TaskRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	ctx, span := startSpan(ctx, "Delete")
+	defer span.End()
+
 	query := `DELETE FROM tasks WHERE id = $1`
 
 	result, err := r.pool.Exec(ctx, query, id)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return fmt.Errorf("failed to delete task: %w", err)
 	}
 
@@ -129,79 +328,86 @@ func (r *TaskRepository) Delete(ctx context.Context, id uuid.UUID) error {
 
 // List возвращает список задач с пагинацией и фильтрацией
 func (r *TaskRepository) List(ctx context.Context, filter domain.TaskFilter, pagination domain.Pagination) (*domain.TaskListResult, error) {
-	// Базовый запрос
-	baseQuery := `FROM tasks WHERE 1=1`
-	args := []any{}
-	argIndex := 1
+	ctx, span := startSpan(ctx, "List")
+	defer span.End()
 
-	// Добавляем фильтр по статусу
-	if filter.Status != nil {
-		baseQuery += fmt.Sprintf(" AND status = $%d", argIndex)
-		args = append(args, *filter.Status)
-		argIndex++
-	}
+	// Базовый запрос
+	qb := newTaskQueryBuilder()
+	qb.applyFilter(filter)
 
 	// Запрос на подсчёт общего количества
-	countQuery := "SELECT COUNT(*) " + baseQuery
 	var total int
-	err := r.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, fmt.Errorf("failed to count tasks: %w", err)
+	if pagination.CountTotal {
+		countQuery := "SELECT COUNT(*) FROM tasks WHERE " + qb.where()
+		if err := r.pool.QueryRow(ctx, countQuery, qb.args...).Scan(&total); err != nil {
+			tracing.RecordError(span, err)
+			return nil, fmt.Errorf("failed to count tasks: %w", err)
+		}
+	}
+
+	sort := filter.Sort
+	if !sort.Field.IsValid() {
+		sort = domain.DefaultTaskSort
 	}
 
-	// Запрос на получение данных
-	selectQuery := fmt.Sprintf(`
-		SELECT id, status, file_key, file_name, content_type, schema, result, error, created_at, updated_at, completed_at
-		%s
-		ORDER BY created_at DESC
-		LIMIT $%d OFFSET $%d
-	`, baseQuery, argIndex, argIndex+1)
+	// Keyset пагинация: продолжаем после последней записи предыдущей страницы
+	if pagination.Keyset && pagination.After != nil {
+		if err := qb.applyCursor(sort, pagination.After); err != nil {
+			return nil, err
+		}
+	}
 
-	args = append(args, pagination.Limit(), pagination.Offset())
+	// Запрос на получение данных. Для keyset берём на одну запись больше,
+	// чтобы понять, есть ли следующая страница
+	limit := pagination.Limit()
+	selectQuery := "SELECT " + taskColumns + " FROM tasks WHERE " + qb.where() + " ORDER BY " + qb.orderBy(sort)
+	if pagination.Keyset {
+		selectQuery += fmt.Sprintf(" LIMIT %s", qb.add(limit+1))
+	} else {
+		selectQuery += fmt.Sprintf(" LIMIT %s OFFSET %s", qb.add(limit), qb.add(pagination.Offset()))
+	}
 
-	rows, err := r.pool.Query(ctx, selectQuery, args...)
+	rows, err := r.pool.Query(ctx, selectQuery, qb.args...)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return nil, fmt.Errorf("failed to query tasks: %w", err)
 	}
 	defer rows.Close()
 
 	tasks := make([]*domain.Task, 0)
 	for rows.Next() {
-		task := &domain.Task{}
-		var errorMsg *string  // Указатель для NULL
-
-		err := rows.Scan(
-			&task.ID,
-			&task.Status,
-			&task.FileKey,
-			&task.FileName,
-			&task.ContentType,
-			&task.Schema,
-			&task.Result,
-			&errorMsg,  // Сканируем в указатель
-			&task.CreatedAt,
-			&task.UpdatedAt,
-			&task.CompletedAt,
-		)
+		task, err := scanTask(rows)
 		if err != nil {
+			tracing.RecordError(span, err)
 			return nil, fmt.Errorf("failed to scan task: %w", err)
 		}
 
-		// Обрабатываем NULL
-		if errorMsg != nil {
-			task.Error = *errorMsg
-		}
-
 		tasks = append(tasks, task)
 	}
 
 	if err := rows.Err(); err != nil {
+		tracing.RecordError(span, err)
 		return nil, fmt.Errorf("rows iteration error: %w", err)
 	}
 
-	return &domain.TaskListResult{
+	result := &domain.TaskListResult{
 		Tasks:      tasks,
 		Total:      total,
 		Pagination: pagination,
-	}, nil
-}
\ No newline at end of file
+	}
+
+	if pagination.Keyset && len(tasks) > limit {
+		result.Tasks = tasks[:limit]
+		result.NextCursor = cursorFor(tasks[limit-1], sort.Field).Encode()
+	}
+
+	return result, nil
+}
+
+// startSpan начинает спан для операции с таблицей задач
+func startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return tracing.Start(ctx, "postgres.tasks."+operation,
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", operation),
+	)
+}
@@ -0,0 +1,363 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/internal/domain"
+)
+
+// Формат зашифрованного объекта:
+//
+//	magic | len(keyID) uint8 | keyID | len(wrappedKey) uint16 | wrappedKey | chunkSize uint32 | chunks...
+//
+// Содержимое делится на чанки по chunkSize байт, каждый шифруется AES-GCM
+// ключом данных объекта. Nonce чанка — его номер и флаг последнего чанка,
+// заголовок передаётся как additional data, поэтому перестановка,
+// обрезка и подмена заголовка обнаруживаются при чтении
+const (
+	encryptionMagic     = "DRENC\x01"
+	encryptionChunkSize = 64 * 1024
+	dataKeySize         = 32
+)
+
+var (
+	ErrCorruptedObject   = errors.New("encrypted object is corrupted")
+	ErrUnencryptedObject = errors.New("object is not encrypted")
+)
+
+// EncryptedStorage декоратор хранилища с envelope шифрованием.
+// Объекты без заголовка отклоняются: их мог подменить тот, у кого есть доступ
+// на запись в бакет. Объекты, загруженные до включения шифрования, читаются
+// как есть только с allowPlaintext на время миграции
+type EncryptedStorage struct {
+	Storage
+	keyring        *Keyring
+	allowPlaintext bool
+}
+
+// NewEncryptedStorage создаёт новый экземпляр EncryptedStorage
+func NewEncryptedStorage(storage Storage, keyring *Keyring, allowPlaintext bool) *EncryptedStorage {
+	return &EncryptedStorage{
+		Storage:        storage,
+		keyring:        keyring,
+		allowPlaintext: allowPlaintext,
+	}
+}
+
+// WithEncryption оборачивает хранилище шифрованием, если оно включено в конфигурации
+func WithEncryption(storage Storage, cfg config.EncryptionConfig) (Storage, error) {
+	if !cfg.Enabled {
+		return storage, nil
+	}
+
+	keyring, err := NewKeyring(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load master keys: %w", err)
+	}
+
+	return NewEncryptedStorage(storage, keyring, cfg.AllowPlaintext), nil
+}
+
+// Upload шифрует и загружает файл
+func (s *EncryptedStorage) Upload(ctx context.Context, fileName string, contentType string, reader io.Reader, size int64) (string, error) {
+	encrypted, encryptedSize, err := s.encrypt(reader, size)
+	if err != nil {
+		return "", err
+	}
+	return s.Storage.Upload(ctx, fileName, contentType, encrypted, encryptedSize)
+}
+
+// Put шифрует и загружает файл под заданным ключом
+func (s *EncryptedStorage) Put(ctx context.Context, fileKey string, contentType string, reader io.Reader, size int64) error {
+	encrypted, encryptedSize, err := s.encrypt(reader, size)
+	if err != nil {
+		return err
+	}
+	return s.Storage.Put(ctx, fileKey, contentType, encrypted, encryptedSize)
+}
+
+// Download скачивает и расшифровывает файл
+func (s *EncryptedStorage) Download(ctx context.Context, fileKey string) (io.ReadCloser, error) {
+	body, err := s.Storage.Download(ctx, fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(body)
+	magic, err := reader.Peek(len(encryptionMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		body.Close()
+		return nil, fmt.Errorf("failed to read object header: %w", err)
+	}
+	if string(magic) != encryptionMagic {
+		if !s.allowPlaintext {
+			body.Close()
+			return nil, fmt.Errorf("%w: %s", ErrUnencryptedObject, fileKey)
+		}
+		// Объект загружен до включения шифрования
+		return readCloser{Reader: reader, Closer: body}, nil
+	}
+
+	decrypted, err := s.decrypt(reader)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("failed to decrypt %s: %w", fileKey, err)
+	}
+
+	return readCloser{Reader: decrypted, Closer: body}, nil
+}
+
+// GetURL недоступен: по прямой ссылке клиент получил бы шифротекст
+func (s *EncryptedStorage) GetURL(_ context.Context, _ string) (string, error) {
+	return "", domain.ErrDirectURLUnavailable
+}
+
+// PresignUpload недоступен: файл, загруженный напрямую, не был бы зашифрован
+func (s *EncryptedStorage) PresignUpload(_ context.Context, _ string, _ time.Duration) (*domain.PresignedUpload, error) {
+	return nil, domain.ErrDirectURLUnavailable
+}
+
+// encrypt возвращает поток зашифрованного содержимого и его размер
+// (-1, если размер исходных данных неизвестен)
+func (s *EncryptedStorage) encrypt(reader io.Reader, size int64) (io.Reader, int64, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, 0, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	keyID, wrapped, err := s.keyring.wrap(dataKey)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	header := encodeHeader(keyID, wrapped, encryptionChunkSize)
+
+	encryptedSize := int64(-1)
+	if size >= 0 {
+		chunks := (size + encryptionChunkSize - 1) / encryptionChunkSize
+		if chunks == 0 {
+			chunks = 1
+		}
+		encryptedSize = int64(len(header)) + size + chunks*int64(aead.Overhead())
+	}
+
+	return io.MultiReader(bytes.NewReader(header), &encryptReader{
+		src:       bufio.NewReaderSize(reader, encryptionChunkSize),
+		aead:      aead,
+		header:    header,
+		chunkSize: encryptionChunkSize,
+	}), encryptedSize, nil
+}
+
+// decrypt разбирает заголовок и возвращает поток расшифрованного содержимого
+func (s *EncryptedStorage) decrypt(reader *bufio.Reader) (io.Reader, error) {
+	header, keyID, wrapped, chunkSize, err := decodeHeader(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := s.keyring.unwrap(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		src:       reader,
+		aead:      aead,
+		header:    header,
+		chunkSize: chunkSize,
+	}, nil
+}
+
+// encodeHeader формирует заголовок зашифрованного объекта
+func encodeHeader(keyID string, wrapped []byte, chunkSize int) []byte {
+	header := make([]byte, 0, len(encryptionMagic)+1+len(keyID)+2+len(wrapped)+4)
+	header = append(header, encryptionMagic...)
+	header = append(header, byte(len(keyID)))
+	header = append(header, keyID...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+	header = binary.BigEndian.AppendUint32(header, uint32(chunkSize))
+	return header
+}
+
+// decodeHeader читает заголовок зашифрованного объекта
+func decodeHeader(reader io.Reader) (header []byte, keyID string, wrapped []byte, chunkSize int, err error) {
+	var buf bytes.Buffer
+	r := io.TeeReader(reader, &buf)
+
+	prefix := make([]byte, len(encryptionMagic)+1)
+	if _, err = io.ReadFull(r, prefix); err != nil {
+		return nil, "", nil, 0, ErrCorruptedObject
+	}
+
+	id := make([]byte, prefix[len(encryptionMagic)])
+	if _, err = io.ReadFull(r, id); err != nil {
+		return nil, "", nil, 0, ErrCorruptedObject
+	}
+
+	var wrappedLen uint16
+	if err = binary.Read(r, binary.BigEndian, &wrappedLen); err != nil {
+		return nil, "", nil, 0, ErrCorruptedObject
+	}
+	wrapped = make([]byte, wrappedLen)
+	if _, err = io.ReadFull(r, wrapped); err != nil {
+		return nil, "", nil, 0, ErrCorruptedObject
+	}
+
+	var size uint32
+	if err = binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, "", nil, 0, ErrCorruptedObject
+	}
+	if size == 0 || size > 16*encryptionChunkSize {
+		return nil, "", nil, 0, ErrCorruptedObject
+	}
+
+	return buf.Bytes(), string(id), wrapped, int(size), nil
+}
+
+// chunkNonce формирует nonce чанка из его номера и признака последнего чанка
+func chunkNonce(aead cipher.AEAD, index uint64, last bool) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce, index)
+	if last {
+		nonce[len(nonce)-1] = 1
+	}
+	return nonce
+}
+
+// isEOF проверяет, что в потоке не осталось данных
+func isEOF(reader *bufio.Reader) (bool, error) {
+	_, err := reader.Peek(1)
+	if errors.Is(err, io.EOF) {
+		return true, nil
+	}
+	return false, err
+}
+
+// encryptReader шифрует поток по чанкам
+type encryptReader struct {
+	src       *bufio.Reader
+	aead      cipher.AEAD
+	header    []byte
+	chunkSize int
+	index     uint64
+	buf       []byte
+	done      bool
+}
+
+func (r *encryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *encryptReader) next() error {
+	plain := make([]byte, r.chunkSize)
+	n, err := io.ReadFull(r.src, plain)
+
+	last := false
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case err != nil:
+		return err
+	default:
+		if last, err = isEOF(r.src); err != nil {
+			return err
+		}
+	}
+
+	r.buf = r.aead.Seal(nil, chunkNonce(r.aead, r.index, last), plain[:n], r.header)
+	r.index++
+	r.done = last
+	return nil
+}
+
+// decryptReader расшифровывает поток по чанкам
+type decryptReader struct {
+	src       *bufio.Reader
+	aead      cipher.AEAD
+	header    []byte
+	chunkSize int
+	index     uint64
+	buf       []byte
+	done      bool
+}
+
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *decryptReader) next() error {
+	sealed := make([]byte, r.chunkSize+r.aead.Overhead())
+	n, err := io.ReadFull(r.src, sealed)
+
+	last := false
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	case err != nil:
+		return err
+	default:
+		if last, err = isEOF(r.src); err != nil {
+			return err
+		}
+	}
+
+	plain, err := r.aead.Open(sealed[:0], chunkNonce(r.aead, r.index, last), sealed[:n], r.header)
+	if err != nil {
+		return ErrCorruptedObject
+	}
+
+	r.buf = plain
+	r.index++
+	r.done = last
+	return nil
+}
+
+// readCloser объединяет поток чтения с закрытием исходного объекта
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
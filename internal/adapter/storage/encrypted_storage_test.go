@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/plastinin/docrecognizer/internal/config"
+)
+
+// memoryStorage хранилище в памяти для тестов шифрования
+type memoryStorage struct {
+	Storage
+	objects map[string][]byte
+}
+
+func (s *memoryStorage) Put(_ context.Context, fileKey string, _ string, reader io.Reader, _ int64) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	s.objects[fileKey] = data
+	return nil
+}
+
+func (s *memoryStorage) Download(_ context.Context, fileKey string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(s.objects[fileKey])), nil
+}
+
+func newTestKeyring(t *testing.T) *Keyring {
+	t.Helper()
+
+	key := make([]byte, masterKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	keyring, err := NewKeyring(config.EncryptionConfig{
+		ActiveKeyID: "k1",
+		Keys:        []string{"k1:" + base64.StdEncoding.EncodeToString(key)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring
+}
+
+func newTestStorage(t *testing.T, allowPlaintext bool) (*EncryptedStorage, *memoryStorage) {
+	t.Helper()
+
+	backend := &memoryStorage{objects: make(map[string][]byte)}
+	return NewEncryptedStorage(backend, newTestKeyring(t), allowPlaintext), backend
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+
+	data := make([]byte, n)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func download(s *EncryptedStorage, key string) ([]byte, error) {
+	body, err := s.Download(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func TestEncryptedStorageRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		size int
+	}{
+		{"empty", 0},
+		{"one byte", 1},
+		{"less than chunk", encryptionChunkSize - 1},
+		{"exact chunk", encryptionChunkSize},
+		{"chunk and one byte", encryptionChunkSize + 1},
+		{"exact multiple chunks", 3 * encryptionChunkSize},
+		{"multiple chunks", 3*encryptionChunkSize + 100},
+	}
+
+	for _, tt := range tests {
+		for _, knownSize := range []bool{true, false} {
+			name := tt.name + "/streamed"
+			if knownSize {
+				name = tt.name + "/known size"
+			}
+			t.Run(name, func(t *testing.T) {
+				s, backend := newTestStorage(t, false)
+				plain := randomBytes(t, tt.size)
+
+				size := int64(-1)
+				if knownSize {
+					size = int64(len(plain))
+				}
+				if err := s.Put(context.Background(), "file", "application/pdf", bytes.NewReader(plain), size); err != nil {
+					t.Fatalf("Put: %v", err)
+				}
+
+				stored := backend.objects["file"]
+				if len(plain) >= 16 && bytes.Contains(stored, plain) {
+					t.Fatal("stored object contains plaintext")
+				}
+
+				// Заявленный размер совпадает с фактическим размером шифртекста
+				if knownSize {
+					_, expected, err := s.encrypt(bytes.NewReader(plain), size)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if expected != int64(len(stored)) {
+						t.Errorf("encrypted size = %d, stored %d bytes", expected, len(stored))
+					}
+				}
+
+				got, err := download(s, "file")
+				if err != nil {
+					t.Fatalf("Download: %v", err)
+				}
+				if !bytes.Equal(got, plain) {
+					t.Errorf("decrypted %d bytes, want %d bytes", len(got), len(plain))
+				}
+			})
+		}
+	}
+}
+
+// chunks возвращает заголовок и шифрованные чанки объекта
+func chunks(t *testing.T, object []byte) (header []byte, sealed [][]byte) {
+	t.Helper()
+
+	header, _, _, chunkSize, err := decodeHeader(bufio.NewReader(bytes.NewReader(object)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rest := object[len(header):]
+	sealedSize := chunkSize + 16 // Overhead AES-GCM
+	for len(rest) > 0 {
+		n := min(sealedSize, len(rest))
+		sealed = append(sealed, rest[:n])
+		rest = rest[n:]
+	}
+	return header, sealed
+}
+
+func TestEncryptedStorageTamper(t *testing.T) {
+	s, backend := newTestStorage(t, false)
+	plain := randomBytes(t, 3*encryptionChunkSize+100)
+	if err := s.Put(context.Background(), "file", "application/pdf", bytes.NewReader(plain), int64(len(plain))); err != nil {
+		t.Fatal(err)
+	}
+	object := backend.objects["file"]
+	header, sealed := chunks(t, object)
+	if len(sealed) != 4 {
+		t.Fatalf("got %d chunks, want 4", len(sealed))
+	}
+
+	join := func(parts ...[]byte) []byte {
+		return bytes.Join(append([][]byte{header}, parts...), nil)
+	}
+
+	tests := []struct {
+		name   string
+		object []byte
+	}{
+		{"truncated to whole chunks", join(sealed[0], sealed[1])},
+		{"last chunk dropped", join(sealed[0], sealed[1], sealed[2])},
+		{"truncated inside chunk", object[:len(object)-10]},
+		{"header only", header},
+		{"chunks reordered", join(sealed[1], sealed[0], sealed[2], sealed[3])},
+		{"chunk duplicated", join(sealed[0], sealed[0], sealed[1], sealed[2], sealed[3])},
+		{"bit flipped", func() []byte {
+			tampered := bytes.Clone(object)
+			tampered[len(header)+100] ^= 1
+			return tampered
+		}()},
+		{"header chunk size changed", func() []byte {
+			tampered := bytes.Clone(object)
+			tampered[len(header)-1] ^= 1
+			return tampered
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend.objects["tampered"] = tt.object
+			if _, err := download(s, "tampered"); err == nil {
+				t.Fatal("expected error for tampered object")
+			}
+		})
+	}
+}
+
+func TestEncryptedStoragePlaintext(t *testing.T) {
+	plain := []byte("%PDF-1.7 plaintext")
+
+	s, backend := newTestStorage(t, false)
+	backend.objects["plain"] = plain
+	if _, err := download(s, "plain"); !errors.Is(err, ErrUnencryptedObject) {
+		t.Fatalf("err = %v, want ErrUnencryptedObject", err)
+	}
+
+	s.allowPlaintext = true
+	got, err := download(s, "plain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Errorf("got %q, want %q", got, plain)
+	}
+}
+
+func TestKeyringSecretIsNotDataKey(t *testing.T) {
+	keyring := newTestKeyring(t)
+
+	sealed, err := keyring.Seal([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened, err := keyring.Open(sealed)
+	if err != nil || string(opened) != "secret" {
+		t.Fatalf("Open = %q, %v", opened, err)
+	}
+
+	// Обёрнутый ключ данных не открывается как секрет и наоборот
+	keyID, wrapped, err := keyring.wrap(randomBytes(t, dataKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	asSecret := append([]byte{byte(len(keyID))}, keyID...)
+	if _, err := keyring.Open(append(asSecret, wrapped...)); err == nil {
+		t.Error("wrapped data key opened as secret")
+	}
+	if _, err := keyring.unwrap(keyID, sealed[1+len(keyID):]); err == nil {
+		t.Error("secret unwrapped as data key")
+	}
+}
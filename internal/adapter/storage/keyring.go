@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/internal/usecase"
+)
+
+// masterKeySize размер мастер-ключа (AES-256)
+const masterKeySize = 32
+
+var ErrUnknownMasterKey = errors.New("unknown master key")
+
+// keyFile формат файла мастер-ключей (совместим с выгрузкой из KMS):
+//
+//	{"active_key_id": "k2", "keys": [{"id": "k1", "key": "<base64>"}, {"id": "k2", "key": "<base64>"}]}
+type keyFile struct {
+	ActiveKeyID string `json:"active_key_id"`
+	Keys        []struct {
+		ID  string `json:"id"`
+		Key string `json:"key"`
+	} `json:"keys"`
+}
+
+// Keyring набор мастер-ключей. Новые ключи данных оборачиваются активным ключом,
+// остальные нужны для чтения объектов, зашифрованных до ротации
+type Keyring struct {
+	activeID string
+	keys     map[string]cipher.AEAD
+}
+
+// NewKeyring загружает мастер-ключи из ENCRYPTION_KEYS и ENCRYPTION_KEYS_FILE
+func NewKeyring(cfg config.EncryptionConfig) (*Keyring, error) {
+	k := &Keyring{
+		activeID: cfg.ActiveKeyID,
+		keys:     make(map[string]cipher.AEAD),
+	}
+
+	for _, entry := range cfg.Keys {
+		id, key, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, errors.New("invalid master key entry: expected id:base64key")
+		}
+		if err := k.add(id, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.KeysFile != "" {
+		if err := k.loadFile(cfg.KeysFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(k.keys) == 0 {
+		return nil, errors.New("no master keys configured")
+	}
+	if _, ok := k.keys[k.activeID]; !ok {
+		return nil, fmt.Errorf("%w: active key %q", ErrUnknownMasterKey, k.activeID)
+	}
+
+	return k, nil
+}
+
+// loadFile читает мастер-ключи из JSON файла
+func (k *Keyring) loadFile(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("failed to read master keys file: %w", err)
+	}
+
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse master keys file: %w", err)
+	}
+
+	for _, entry := range file.Keys {
+		if err := k.add(entry.ID, entry.Key); err != nil {
+			return err
+		}
+	}
+	if k.activeID == "" {
+		k.activeID = file.ActiveKeyID
+	}
+
+	return nil
+}
+
+// add добавляет мастер-ключ в base64
+func (k *Keyring) add(id, encoded string) error {
+	if id == "" || len(id) > 255 {
+		return fmt.Errorf("invalid master key id %q", id)
+	}
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("duplicate master key id %q", id)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("invalid master key %q: %w", id, err)
+	}
+	if len(key) != masterKeySize {
+		return fmt.Errorf("invalid master key %q: must be %d bytes", id, masterKeySize)
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	k.keys[id] = aead
+
+	return nil
+}
+
+// ActiveKeyID возвращает ID ключа, которым шифруются новые объекты
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// Назначение шифртекста мастер-ключа входит в additional data, чтобы обёрнутый
+// ключ данных нельзя было расшифровать как секрет задачи и наоборот
+const (
+	purposeDataKey = ""        // Пусто для совместимости с объектами, зашифрованными ранее
+	purposeSecret  = "secret:" // Пароли документов
+)
+
+// wrap оборачивает ключ данных активным мастер-ключом.
+// ID ключа входит в additional data, чтобы обёртку нельзя было подменить
+func (k *Keyring) wrap(dataKey []byte) (string, []byte, error) {
+	return k.seal(dataKey, purposeDataKey)
+}
+
+// unwrap восстанавливает ключ данных
+func (k *Keyring) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	dataKey, err := k.open(keyID, wrapped, purposeDataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return dataKey, nil
+}
+
+// seal шифрует данные активным мастер-ключом; additional data — назначение и ID ключа
+func (k *Keyring) seal(plaintext []byte, purpose string) (string, []byte, error) {
+	aead := k.keys[k.activeID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return k.activeID, aead.Seal(nonce, nonce, plaintext, []byte(purpose+k.activeID)), nil
+}
+
+// open расшифровывает данные, зашифрованные seal с тем же назначением
+func (k *Keyring) open(keyID string, sealed []byte, purpose string) ([]byte, error) {
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMasterKey, keyID)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sealed data is too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, []byte(purpose+keyID))
+}
+
+// Seal шифрует секрет задачи (пароль документа) активным мастер-ключом.
+// Формат: длина ID ключа (1 байт), ID ключа, nonce и шифртекст
+func (k *Keyring) Seal(plaintext []byte) ([]byte, error) {
+	keyID, sealed, err := k.seal(plaintext, purposeSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 1+len(keyID)+len(sealed))
+	out = append(out, byte(len(keyID)))
+	out = append(out, keyID...)
+	return append(out, sealed...), nil
+}
+
+// Open расшифровывает секрет, зашифрованный Seal, в том числе ключом до ротации
+func (k *Keyring) Open(sealed []byte) ([]byte, error) {
+	if len(sealed) == 0 || len(sealed) < 1+int(sealed[0]) {
+		return nil, errors.New("sealed secret is too short")
+	}
+	keyIDLen := int(sealed[0])
+	keyID := string(sealed[1 : 1+keyIDLen])
+	plaintext, err := k.open(keyID, sealed[1+keyIDLen:], purposeSecret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open secret: %w", err)
+	}
+	return plaintext, nil
+}
+
+// NewSecretSealer создаёт шифрование секретов задач мастер-ключами ENCRYPTION_KEYS.
+// Ключи используются независимо от ENCRYPTION_ENABLED. Возвращает nil,
+// если мастер-ключи не настроены: тогда пароли документов не принимаются
+func NewSecretSealer(cfg config.EncryptionConfig) (usecase.SecretSealer, error) {
+	if len(cfg.Keys) == 0 && cfg.KeysFile == "" {
+		return nil, nil
+	}
+
+	keyring, err := NewKeyring(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load master keys: %w", err)
+	}
+	return keyring, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return aead, nil
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/pkg/metrics"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// FilesPathPrefix путь API, по которому отдаются файлы локального хранилища
+const FilesPathPrefix = "/files/"
+
+// getURLExpiry срок действия ссылки на скачивание (как у S3Storage.GetURL)
+const getURLExpiry = time.Hour
+
+// LocalStorage реализация файлового хранилища в локальной файловой системе.
+// Ключи совпадают с S3Storage, временные ссылки подписываются и обслуживаются API
+type LocalStorage struct {
+	root   string
+	signer *urlSigner
+}
+
+// NewLocalStorage создаёт новый экземпляр LocalStorage
+func NewLocalStorage(cfg config.StorageConfig) (*LocalStorage, error) {
+	if cfg.SigningKey == "" {
+		return nil, errors.New("STORAGE_SIGNING_KEY is required for local storage")
+	}
+
+	root, err := filepath.Abs(cfg.LocalRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage root: %w", err)
+	}
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage root: %w", err)
+	}
+
+	return &LocalStorage{
+		root:   root,
+		signer: newURLSigner(cfg.SigningKey, cfg.PublicURL),
+	}, nil
+}
+
+// Upload сохраняет файл и возвращает ключ
+func (s *LocalStorage) Upload(ctx context.Context, fileName string, contentType string, reader io.Reader, size int64) (string, error) {
+	fileKey := newFileKey(fileName)
+	if err := s.Put(ctx, fileKey, contentType, reader, size); err != nil {
+		return "", err
+	}
+	return fileKey, nil
+}
+
+// Put атомарно сохраняет файл под заданным ключом: данные пишутся во временный
+// файл в том же каталоге и переименовываются после успешной записи
+func (s *LocalStorage) Put(ctx context.Context, fileKey string, _ string, reader io.Reader, _ int64) (err error) {
+	_, span := s.startSpan(ctx, "Put", attribute.String("file_key", fileKey))
+	defer span.End()
+
+	start := time.Now()
+	defer func() {
+		metrics.ObserveStorageOperation("upload", start, err)
+		tracing.RecordError(span, err)
+	}()
+
+	filePath, err := s.path(fileKey)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o750); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = io.Copy(tmp, reader); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("failed to sync file: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+
+	return nil
+}
+
+// Download открывает файл для чтения
+func (s *LocalStorage) Download(ctx context.Context, fileKey string) (io.ReadCloser, error) {
+	_, span := s.startSpan(ctx, "Download", attribute.String("file_key", fileKey))
+	defer span.End()
+
+	filePath, err := s.path(fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	file, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		metrics.ObserveStorageOperation("download", start, nil)
+		return nil, domain.ErrFileNotFound
+	}
+	metrics.ObserveStorageOperation("download", start, err)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return file, nil
+}
+
+// Delete удаляет файл; отсутствие файла ошибкой не считается, как и в S3
+func (s *LocalStorage) Delete(ctx context.Context, fileKey string) error {
+	_, span := s.startSpan(ctx, "Delete", attribute.String("file_key", fileKey))
+	defer span.End()
+
+	filePath, err := s.path(fileKey)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = os.Remove(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		err = nil
+	}
+	metrics.ObserveStorageOperation("delete", start, err)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return nil
+}
+
+// GetURL возвращает подписанную ссылку на скачивание файла через API
+func (s *LocalStorage) GetURL(_ context.Context, fileKey string) (string, error) {
+	if _, err := s.path(fileKey); err != nil {
+		return "", err
+	}
+	return s.signer.sign(http.MethodGet, fileKey, time.Now().Add(getURLExpiry)), nil
+}
+
+// PresignUpload возвращает подписанную ссылку для загрузки файла через API
+func (s *LocalStorage) PresignUpload(_ context.Context, fileName string, expiry time.Duration) (*domain.PresignedUpload, error) {
+	fileKey := newUploadKey(fileName)
+	expiresAt := time.Now().Add(expiry)
+
+	return &domain.PresignedUpload{
+		FileKey:   fileKey,
+		URL:       s.signer.sign(http.MethodPut, fileKey, expiresAt),
+		Method:    http.MethodPut,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// Stat возвращает метаданные файла. Тип содержимого определяется по расширению
+func (s *LocalStorage) Stat(ctx context.Context, fileKey string) (*domain.FileInfo, error) {
+	_, span := s.startSpan(ctx, "Stat", attribute.String("file_key", fileKey))
+	defer span.End()
+
+	filePath, err := s.path(fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	info, err := os.Stat(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		metrics.ObserveStorageOperation("stat", start, nil)
+		return nil, domain.ErrFileNotFound
+	}
+	metrics.ObserveStorageOperation("stat", start, err)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return &domain.FileInfo{
+		Key:         fileKey,
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(fileKey)),
+	}, nil
+}
+
+// VerifyURL проверяет подпись ссылки, выданной GetURL или PresignUpload
+func (s *LocalStorage) VerifyURL(method, fileKey, expires, signature string) error {
+	return s.signer.verify(method, fileKey, expires, signature)
+}
+
+// CheckHealth проверяет доступность корневого каталога
+func (s *LocalStorage) CheckHealth(_ context.Context) error {
+	info, err := os.Stat(s.root)
+	if err != nil {
+		return fmt.Errorf("failed to stat storage root: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("storage root %s is not a directory", s.root)
+	}
+	return nil
+}
+
+// path возвращает путь к файлу, не позволяя ключу выйти за пределы корневого каталога
+func (s *LocalStorage) path(fileKey string) (string, error) {
+	cleaned := path.Clean("/" + fileKey)
+	if fileKey == "" || cleaned == "/" || strings.Contains(fileKey, "\\") {
+		return "", fmt.Errorf("%w: %q", domain.ErrInvalidUploadKey, fileKey)
+	}
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+// startSpan начинает спан для операции с файловой системой
+func (s *LocalStorage) startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs, attribute.String("storage.operation", operation))
+	return tracing.Start(ctx, "fs."+operation, attrs...)
+}
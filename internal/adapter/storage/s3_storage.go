@@ -4,15 +4,24 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"path"
+	"net/http"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/pkg/metrics"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// streamPartSize размер части multipart загрузки, если размер файла неизвестен.
+// По умолчанию minio-go рассчитывает часть на максимальный размер объекта
+// (сотни мегабайт) и держит её в памяти целиком
+const streamPartSize = 5 << 20 // 5 MB
+
 // S3Storage реализация файлового хранилища на базе S3/MinIO
 type S3Storage struct {
 	client *minio.Client
@@ -50,37 +59,59 @@ func NewS3Storage(ctx context.Context, cfg config.S3Config) (*S3Storage, error)
 
 // Upload загружает файл в S3 и возвращает ключ
 func (s *S3Storage) Upload(ctx context.Context, fileName string, contentType string, reader io.Reader, size int64) (string, error) {
+	ctx, span := s.startSpan(ctx, "Upload")
+	defer span.End()
+
 	// Генерируем уникальный ключ: year/month/day/uuid/filename
-	now := time.Now()
-	fileKey := path.Join(
-		now.Format("2006"),
-		now.Format("01"),
-		now.Format("02"),
-		uuid.New().String(),
-		fileName,
-	)
+	fileKey := newFileKey(fileName)
+	span.SetAttributes(attribute.String("file_key", fileKey))
 
-	_, err := s.client.PutObject(ctx, s.bucket, fileKey, reader, size, minio.PutObjectOptions{
-		ContentType: contentType,
-	})
+	start := time.Now()
+	_, err := s.client.PutObject(ctx, s.bucket, fileKey, reader, size, putOptions(contentType, size))
+	metrics.ObserveStorageOperation("upload", start, err)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return "", fmt.Errorf("failed to upload file: %w", err)
 	}
 
 	return fileKey, nil
 }
 
+// Put загружает файл в S3 под заданным ключом
+func (s *S3Storage) Put(ctx context.Context, fileKey string, contentType string, reader io.Reader, size int64) error {
+	ctx, span := s.startSpan(ctx, "Put", attribute.String("file_key", fileKey))
+	defer span.End()
+
+	start := time.Now()
+	_, err := s.client.PutObject(ctx, s.bucket, fileKey, reader, size, putOptions(contentType, size))
+	metrics.ObserveStorageOperation("upload", start, err)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	return nil
+}
+
 // Download скачивает файл из S3
 func (s *S3Storage) Download(ctx context.Context, fileKey string) (io.ReadCloser, error) {
+	ctx, span := s.startSpan(ctx, "Download", attribute.String("file_key", fileKey))
+	defer span.End()
+
+	start := time.Now()
 	obj, err := s.client.GetObject(ctx, s.bucket, fileKey, minio.GetObjectOptions{})
 	if err != nil {
+		metrics.ObserveStorageOperation("download", start, err)
+		tracing.RecordError(span, err)
 		return nil, fmt.Errorf("failed to get object: %w", err)
 	}
 
 	// Проверяем, что объект существует
 	_, err = obj.Stat()
+	metrics.ObserveStorageOperation("download", start, err)
 	if err != nil {
 		obj.Close()
+		tracing.RecordError(span, err)
 		return nil, fmt.Errorf("failed to stat object: %w", err)
 	}
 
@@ -89,8 +120,14 @@ func (s *S3Storage) Download(ctx context.Context, fileKey string) (io.ReadCloser
 
 // Delete удаляет файл из S3
 func (s *S3Storage) Delete(ctx context.Context, fileKey string) error {
+	ctx, span := s.startSpan(ctx, "Delete", attribute.String("file_key", fileKey))
+	defer span.End()
+
+	start := time.Now()
 	err := s.client.RemoveObject(ctx, s.bucket, fileKey, minio.RemoveObjectOptions{})
+	metrics.ObserveStorageOperation("delete", start, err)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return fmt.Errorf("failed to delete object: %w", err)
 	}
 	return nil
@@ -98,13 +135,99 @@ func (s *S3Storage) Delete(ctx context.Context, fileKey string) error {
 
 // GetURL возвращает presigned URL для доступа к файлу
 func (s *S3Storage) GetURL(ctx context.Context, fileKey string) (string, error) {
+	ctx, span := s.startSpan(ctx, "GetURL", attribute.String("file_key", fileKey))
+	defer span.End()
+
 	// URL действителен 1 час
 	expiry := time.Hour
 
+	start := time.Now()
 	url, err := s.client.PresignedGetObject(ctx, s.bucket, fileKey, expiry, nil)
+	metrics.ObserveStorageOperation("presign", start, err)
 	if err != nil {
+		tracing.RecordError(span, err)
 		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
 	}
 
 	return url.String(), nil
-}
\ No newline at end of file
+}
+
+// PresignUpload возвращает presigned PUT URL для прямой загрузки файла клиентом
+func (s *S3Storage) PresignUpload(ctx context.Context, fileName string, expiry time.Duration) (*domain.PresignedUpload, error) {
+	ctx, span := s.startSpan(ctx, "PresignUpload")
+	defer span.End()
+
+	fileKey := newUploadKey(fileName)
+	span.SetAttributes(attribute.String("file_key", fileKey))
+
+	start := time.Now()
+	url, err := s.client.PresignedPutObject(ctx, s.bucket, fileKey, expiry)
+	metrics.ObserveStorageOperation("presign_upload", start, err)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to generate presigned upload URL: %w", err)
+	}
+
+	return &domain.PresignedUpload{
+		FileKey:   fileKey,
+		URL:       url.String(),
+		Method:    http.MethodPut,
+		ExpiresAt: start.Add(expiry),
+	}, nil
+}
+
+// Stat возвращает метаданные файла
+func (s *S3Storage) Stat(ctx context.Context, fileKey string) (*domain.FileInfo, error) {
+	ctx, span := s.startSpan(ctx, "Stat", attribute.String("file_key", fileKey))
+	defer span.End()
+
+	start := time.Now()
+	info, err := s.client.StatObject(ctx, s.bucket, fileKey, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			metrics.ObserveStorageOperation("stat", start, nil)
+			return nil, domain.ErrFileNotFound
+		}
+		metrics.ObserveStorageOperation("stat", start, err)
+		tracing.RecordError(span, err)
+		return nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+	metrics.ObserveStorageOperation("stat", start, nil)
+
+	return &domain.FileInfo{
+		Key:         fileKey,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+	}, nil
+}
+
+// CheckHealth проверяет доступ к bucket
+func (s *S3Storage) CheckHealth(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return fmt.Errorf("failed to check bucket: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket %s does not exist", s.bucket)
+	}
+	return nil
+}
+
+// startSpan начинает спан для операции с S3
+func (s *S3Storage) startSpan(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String("s3.bucket", s.bucket),
+		attribute.String("s3.operation", operation),
+	)
+	return tracing.Start(ctx, "s3."+operation, attrs...)
+}
+
+// putOptions параметры загрузки объекта. Для файлов неизвестного размера
+// используется multipart загрузка небольшими частями
+func putOptions(contentType string, size int64) minio.PutObjectOptions {
+	opts := minio.PutObjectOptions{ContentType: contentType}
+	if size < 0 {
+		opts.PartSize = streamPartSize
+	}
+	return opts
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/plastinin/docrecognizer/internal/domain"
+)
+
+// urlSigner подписывает временные ссылки на файлы HMAC-SHA256.
+// Подпись покрывает HTTP метод, ключ файла и срок действия
+type urlSigner struct {
+	key     []byte
+	baseURL string
+}
+
+func newURLSigner(key, baseURL string) *urlSigner {
+	return &urlSigner{
+		key:     []byte(key),
+		baseURL: strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// sign возвращает подписанную ссылку вида {baseURL}/files/{key}?expires=...&signature=...
+func (s *urlSigner) sign(method, fileKey string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.signature(method, fileKey, expires))
+
+	return s.baseURL + FilesPathPrefix + (&url.URL{Path: fileKey}).EscapedPath() + "?" + query.Encode()
+}
+
+// verify проверяет подпись и срок действия ссылки
+func (s *urlSigner) verify(method, fileKey, expires, signature string) error {
+	expected := s.signature(method, fileKey, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return domain.ErrInvalidSignature
+	}
+
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return domain.ErrInvalidSignature
+	}
+	if time.Now().Unix() > expiresAt {
+		return domain.ErrSignedURLExpired
+	}
+
+	return nil
+}
+
+func (s *urlSigner) signature(method, fileKey, expires string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(method + "\n" + fileKey + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/plastinin/docrecognizer/internal/config"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/internal/usecase"
+)
+
+// Поддерживаемые хранилища файлов
+const (
+	BackendS3    = "s3"
+	BackendLocal = "local"
+)
+
+// Storage файловое хранилище с проверкой доступности
+type Storage interface {
+	usecase.FileStorage
+	CheckHealth(ctx context.Context) error
+}
+
+// New создаёт хранилище, выбранное в конфигурации
+func New(ctx context.Context, cfg config.StorageConfig, s3Cfg config.S3Config) (Storage, error) {
+	switch cfg.Backend {
+	case BackendS3:
+		return NewS3Storage(ctx, s3Cfg)
+	case BackendLocal:
+		return NewLocalStorage(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Backend)
+	}
+}
+
+// newFileKey генерирует уникальный ключ файла: year/month/day/uuid/filename
+func newFileKey(fileName string) string {
+	now := time.Now()
+	return path.Join(
+		now.Format("2006"),
+		now.Format("01"),
+		now.Format("02"),
+		uuid.New().String(),
+		path.Base(fileName),
+	)
+}
+
+// newUploadKey генерирует ключ файла прямой загрузки: uploads/uuid/filename.
+// Префикс отличает файлы, загруженные клиентом, от файлов, сохранённых сервисом
+func newUploadKey(fileName string) string {
+	return path.Join(domain.UploadKeyPrefix, uuid.New().String(), path.Base(fileName))
+}
@@ -0,0 +1,89 @@
+package templates
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/plastinin/docrecognizer/internal/domain"
+)
+
+// templatesFile формат файла шаблонов:
+//
+//	{"templates": [{"name": "drawing", "render": {"dpi": 100, "max_width": 4000}, "input_mode": "hybrid"},
+//	  {"name": "invoice", "description": "Счёт на оплату",
+//	   "schema": ["invoice_number", "date", "total_amount", "items[description, quantity, price, amount=total_amount]"]}]}
+//
+// Шаблоны со схемой полей участвуют в классификации документов
+type templatesFile struct {
+	Templates []*domain.Template `json:"templates"`
+}
+
+// FileRegistry шаблоны документов, загруженные из JSON файла при старте
+type FileRegistry struct {
+	templates map[string]*domain.Template
+}
+
+// LoadFile загружает шаблоны из файла. Пустой путь — шаблоны не настроены
+func LoadFile(path string) (*FileRegistry, error) {
+	registry := &FileRegistry{templates: make(map[string]*domain.Template)}
+	if path == "" {
+		return registry, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read templates file: %w", err)
+	}
+
+	var file templatesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse templates file: %w", err)
+	}
+
+	for _, template := range file.Templates {
+		if template.Name == "" {
+			return nil, fmt.Errorf("template name is required")
+		}
+		if _, ok := registry.templates[template.Name]; ok {
+			return nil, fmt.Errorf("duplicate template %q", template.Name)
+		}
+		if err := template.Render.Validate(); err != nil {
+			return nil, fmt.Errorf("template %q: %w", template.Name, err)
+		}
+		if _, err := domain.ParseInputMode(template.InputMode.String()); err != nil {
+			return nil, fmt.Errorf("template %q: %w: %s", template.Name, err, template.InputMode)
+		}
+		if _, err := domain.ParseSchema(template.Schema); err != nil {
+			return nil, fmt.Errorf("template %q: %w", template.Name, err)
+		}
+		registry.templates[template.Name] = template
+	}
+
+	return registry, nil
+}
+
+// Get возвращает шаблон по имени
+func (r *FileRegistry) Get(name string) (*domain.Template, bool) {
+	template, ok := r.templates[name]
+	return template, ok
+}
+
+// List возвращает все шаблоны, отсортированные по имени
+func (r *FileRegistry) List() []*domain.Template {
+	list := make([]*domain.Template, 0, len(r.templates))
+	for _, template := range r.templates {
+		list = append(list, template)
+	}
+	slices.SortFunc(list, func(a, b *domain.Template) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return list
+}
+
+// Len возвращает количество шаблонов
+func (r *FileRegistry) Len() int {
+	return len(r.templates)
+}
@@ -9,12 +9,22 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	S3       S3Config
-	Ollama   OllamaConfig
-	Log      LogConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	Redis      RedisConfig
+	Storage    StorageConfig
+	Encryption EncryptionConfig
+	S3         S3Config
+	Ollama     OllamaConfig
+	Worker     WorkerConfig
+	Tracing    TracingConfig
+	Retention  RetentionConfig
+	Cache      CacheConfig
+	Upload     UploadConfig
+	Render     RenderConfig
+	Templates  TemplateConfig
+	Prompts    PromptConfig
+	Log        LogConfig
 }
 
 type ServerConfig struct {
@@ -23,6 +33,10 @@ type ServerConfig struct {
 	ReadTimeout     time.Duration `env:"SERVER_READ_TIMEOUT" envDefault:"30s"`
 	WriteTimeout    time.Duration `env:"SERVER_WRITE_TIMEOUT" envDefault:"30s"`
 	ShutdownTimeout time.Duration `env:"SERVER_SHUTDOWN_TIMEOUT" envDefault:"10s"`
+	// Таймаут проверки зависимостей в /health/ready
+	HealthTimeout time.Duration `env:"SERVER_HEALTH_TIMEOUT" envDefault:"3s"`
+	// Bearer токен API администрирования очереди; пустой — API администрирования отключён
+	AdminToken string `env:"SERVER_ADMIN_TOKEN"`
 }
 
 func (s ServerConfig) Addr() string {
@@ -39,6 +53,7 @@ type DatabaseConfig struct {
 	MaxConns        int           `env:"DB_MAX_CONNS" envDefault:"10"`
 	MinConns        int           `env:"DB_MIN_CONNS" envDefault:"2"`
 	MaxConnLifetime time.Duration `env:"DB_MAX_CONN_LIFETIME" envDefault:"1h"`
+	AutoMigrate     bool          `env:"DB_AUTO_MIGRATE" envDefault:"false"` // Применять миграции при старте API
 }
 
 func (d DatabaseConfig) DSN() string {
@@ -59,6 +74,30 @@ func (r RedisConfig) Addr() string {
 	return fmt.Sprintf("%s:%d", r.Host, r.Port)
 }
 
+type StorageConfig struct {
+	// Хранилище файлов: s3 или local (файловая система)
+	Backend string `env:"STORAGE_BACKEND" envDefault:"s3"`
+	// Корневой каталог для local (общий для API и воркера)
+	LocalRoot string `env:"STORAGE_LOCAL_ROOT" envDefault:"./data/files"`
+	// Ключ подписи временных ссылок на файлы для local
+	SigningKey string `env:"STORAGE_SIGNING_KEY"`
+	// Внешний адрес API, по которому доступны подписанные ссылки
+	PublicURL string `env:"STORAGE_PUBLIC_URL" envDefault:"http://localhost:8080"`
+}
+
+type EncryptionConfig struct {
+	// Envelope шифрование файлов в хранилище
+	Enabled bool `env:"ENCRYPTION_ENABLED" envDefault:"false"`
+	// ID мастер-ключа для новых объектов; старые ключи остаются для чтения
+	ActiveKeyID string `env:"ENCRYPTION_ACTIVE_KEY_ID"`
+	// Мастер-ключи в формате id:base64 через запятую
+	Keys []string `env:"ENCRYPTION_KEYS" envSeparator:","`
+	// JSON файл с мастер-ключами (формат выгрузки из KMS)
+	KeysFile string `env:"ENCRYPTION_KEYS_FILE"`
+	// Читать незашифрованные объекты, загруженные до включения шифрования (только на время миграции)
+	AllowPlaintext bool `env:"ENCRYPTION_ALLOW_PLAINTEXT" envDefault:"false"`
+}
+
 type S3Config struct {
 	Endpoint  string `env:"S3_ENDPOINT" envDefault:"localhost:9000"`
 	AccessKey string `env:"S3_ACCESS_KEY" envDefault:"minioadmin"`
@@ -73,6 +112,104 @@ type OllamaConfig struct {
 	RequestTimeout time.Duration `env:"OLLAMA_REQUEST_TIMEOUT" envDefault:"5m"`
 }
 
+type WorkerConfig struct {
+	// Количество одновременно обрабатываемых задач (для CPU режима лучше меньше)
+	Concurrency int `env:"WORKER_CONCURRENCY" envDefault:"2"`
+	// Очередь, в которую ставятся задачи распознавания
+	Queue string `env:"WORKER_QUEUE" envDefault:"recognition"`
+	// Приоритеты очередей в формате name:weight,name:weight
+	Queues         map[string]int `env:"WORKER_QUEUES" envDefault:"recognition:10,default:1"`
+	StrictPriority bool           `env:"WORKER_STRICT_PRIORITY" envDefault:"false"`
+	MaxRetry       int            `env:"WORKER_MAX_RETRY" envDefault:"3"`
+	// Таймаут обработки одной попытки задачи
+	TaskTimeout time.Duration `env:"WORKER_TASK_TIMEOUT" envDefault:"15m"`
+	// Крайний срок обработки задачи от момента постановки в очередь (0 — без ограничения)
+	TaskDeadline time.Duration `env:"WORKER_TASK_DEADLINE" envDefault:"0s"`
+	// Параметры экспоненциальной задержки между попытками
+	RetryBaseDelay time.Duration `env:"WORKER_RETRY_BASE_DELAY" envDefault:"10s"`
+	RetryMaxDelay  time.Duration `env:"WORKER_RETRY_MAX_DELAY" envDefault:"10m"`
+	// Доля случайного разброса задержки (0.2 — ±20%)
+	RetryJitter     float64       `env:"WORKER_RETRY_JITTER" envDefault:"0.2"`
+	ShutdownTimeout time.Duration `env:"WORKER_SHUTDOWN_TIMEOUT" envDefault:"30s"`
+	// Сколько данных одной задачи держать в памяти: исходный файл и изображения страниц
+	// делят этот лимит, всё, что в него не помещается, сохраняется во временные файлы
+	TaskMemoryLimit int64 `env:"WORKER_TASK_MEMORY_LIMIT" envDefault:"33554432"`
+	// Каталог временных файлов (по умолчанию системный)
+	TempDir string `env:"WORKER_TEMP_DIR"`
+	// HTTP сервер воркера (метрики и health check)
+	HTTPHost string `env:"WORKER_HTTP_HOST" envDefault:"0.0.0.0"`
+	HTTPPort int    `env:"WORKER_HTTP_PORT" envDefault:"8081"`
+}
+
+func (w WorkerConfig) HTTPAddr() string {
+	return fmt.Sprintf("%s:%d", w.HTTPHost, w.HTTPPort)
+}
+
+type RenderConfig struct {
+	// Параметры рендеринга PDF по умолчанию (переопределяются шаблоном и задачей)
+	DPI       int    `env:"PDF_RENDER_DPI" envDefault:"300"`
+	Pages     string `env:"PDF_RENDER_PAGES" envDefault:"1"`
+	MaxWidth  int    `env:"PDF_RENDER_MAX_WIDTH" envDefault:"0"`
+	MaxHeight int    `env:"PDF_RENDER_MAX_HEIGHT" envDefault:"0"`
+	// Входные данные модели для PDF: image, text (текстовый слой) или hybrid
+	InputMode string `env:"PDF_INPUT_MODE" envDefault:"image"`
+	// Максимум страниц, отправляемых в модель за одну задачу
+	MaxPages int `env:"PDF_RENDER_MAX_PAGES" envDefault:"10"`
+	// Максимум страниц файла, разделяемого на несколько документов
+	SplitMaxPages int `env:"PDF_SPLIT_MAX_PAGES" envDefault:"200"`
+}
+
+type TemplateConfig struct {
+	// JSON файл с настройками шаблонов документов
+	File string `env:"TEMPLATES_FILE"`
+	// Минимальная уверенность классификации (0..1), при которой используется схема предсказанного типа
+	MinConfidence float64 `env:"CLASSIFICATION_MIN_CONFIDENCE" envDefault:"0.5"`
+}
+
+type PromptConfig struct {
+	// Каталог с промптами <имя>.tmpl, заменяющими встроенные
+	Dir string `env:"PROMPTS_DIR"`
+	// Загружать промпты из таблицы prompt_templates (приоритетнее каталога)
+	FromDB bool `env:"PROMPTS_DB" envDefault:"false"`
+	// Язык документов для промпта распознавания по умолчанию (переопределяется шаблоном)
+	Language string `env:"PROMPT_LANGUAGE"`
+}
+
+type UploadConfig struct {
+	// Максимальный размер файла для загрузки по URL и прямой загрузки в S3
+	MaxSize int64 `env:"UPLOAD_MAX_SIZE" envDefault:"104857600"`
+	// Срок действия presigned URL для прямой загрузки
+	PresignTTL time.Duration `env:"UPLOAD_PRESIGN_TTL" envDefault:"15m"`
+	// Таймаут скачивания документа по URL
+	FetchTimeout time.Duration `env:"UPLOAD_FETCH_TIMEOUT" envDefault:"60s"`
+	// Разрешить скачивание с приватных адресов (только для локальной разработки)
+	FetchAllowPrivate bool `env:"UPLOAD_FETCH_ALLOW_PRIVATE" envDefault:"false"`
+}
+
+type CacheConfig struct {
+	// Кэш результатов распознавания в Redis
+	Enabled bool          `env:"RECOGNITION_CACHE_ENABLED" envDefault:"false"`
+	TTL     time.Duration `env:"RECOGNITION_CACHE_TTL" envDefault:"168h"`
+}
+
+type RetentionConfig struct {
+	Enabled bool `env:"RETENTION_ENABLED" envDefault:"false"`
+	// Периодичность запуска очистки
+	Interval  time.Duration `env:"RETENTION_INTERVAL" envDefault:"1h"`
+	BatchSize int           `env:"RETENTION_BATCH_SIZE" envDefault:"100"`
+	// Политики в формате status:age:action[:tenant] через запятую,
+	// например completed:30d:delete_file,failed:7d:delete,completed:7d:purge_result:acme.
+	// Политики арендатора заменяют для него политики без арендатора с тем же статусом
+	Policies []string `env:"RETENTION_POLICIES" envSeparator:","`
+}
+
+type TracingConfig struct {
+	Enabled bool `env:"OTEL_TRACING_ENABLED" envDefault:"false"`
+	// URL OTLP/HTTP коллектора
+	Endpoint    string  `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"http://localhost:4318"`
+	SampleRatio float64 `env:"OTEL_TRACES_SAMPLER_RATIO" envDefault:"1"`
+}
+
 type LogConfig struct {
 	Level string `env:"LOG_LEVEL" envDefault:"info"`
 	// json или console
@@ -90,4 +227,4 @@ func Load() (*Config, error) {
 	}
 
 	return cfg, nil
-}
\ No newline at end of file
+}
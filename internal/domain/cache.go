@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+)
+
+// CacheStatus результат обращения к кэшу распознавания для задачи
+type CacheStatus string
+
+const (
+	CacheStatusHit    CacheStatus = "hit"    // Результат взят из кэша
+	CacheStatusMiss   CacheStatus = "miss"   // Результата не было, вызвана модель
+	CacheStatusBypass CacheStatus = "bypass" // Кэш пропущен по запросу, результат обновлён
+)
+
+func (s CacheStatus) String() string {
+	return string(s)
+}
+
+// RecognitionCacheKey строит ключ кэша по SHA-256 изображения,
+// нормализованной схеме, модели и версии промпта
+func RecognitionCacheKey(imageHash []byte, schema []string, model, promptVersion string) string {
+	// Порядок и повторы полей схемы не влияют на результат
+	fields := make([]string, 0, len(schema))
+	for _, field := range schema {
+		fields = append(fields, strings.TrimSpace(field))
+	}
+	slices.Sort(fields)
+	fields = slices.Compact(fields)
+
+	h := sha256.New()
+	h.Write(imageHash)
+	for _, part := range []string{strings.Join(fields, "\x00"), model, promptVersion} {
+		h.Write([]byte{0})
+		h.Write([]byte(part))
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "errors"
+
+var (
+	ErrUnknownDocumentType = errors.New("document type could not be determined")
+	ErrNoDocumentTypes     = errors.New("no document types configured for classification")
+)
+
+// ErrorCodeUnknownDocumentType документ не подошёл ни к одному из настроенных типов
+const ErrorCodeUnknownDocumentType = "unknown_document_type"
+
+// Classification результат классификации документа моделью
+type Classification struct {
+	DocumentType string  // Имя шаблона; пустое, если ни один тип не подошёл
+	Confidence   float64 // Уверенность модели от 0 до 1
+}
+
+// ClassifyTemplates возвращает шаблоны, пригодные как типы для классификации:
+// со схемой полей, по которой затем извлекаются данные
+func ClassifyTemplates(templates []*Template) []*Template {
+	var types []*Template
+	for _, template := range templates {
+		if len(template.Schema) > 0 {
+			types = append(types, template)
+		}
+	}
+	return types
+}
+
+// ApplyClassification сохраняет предсказанный тип документа и переключает
+// извлечение на схему полей этого типа
+func (t *Task) ApplyClassification(c Classification, template *Template) {
+	t.DocumentType = c.DocumentType
+	t.ClassificationConfidence = c.Confidence
+	t.Schema = template.Schema
+}
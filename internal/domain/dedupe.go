@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"maps"
+	"time"
+)
+
+var ErrInvalidDedupeMode = errors.New("invalid dedupe mode")
+
+// DedupeMode режим обработки повторно загруженного документа
+type DedupeMode string
+
+const (
+	DedupeModeOff   DedupeMode = "off"   // Всегда распознавать заново
+	DedupeModeReuse DedupeMode = "reuse" // Вернуть существующую завершённую задачу
+	DedupeModeLink  DedupeMode = "link"  // Создать новую задачу со ссылкой на готовый результат
+)
+
+// ParseDedupeMode разбирает режим дедупликации; пустая строка — режим off
+func ParseDedupeMode(s string) (DedupeMode, error) {
+	switch mode := DedupeMode(s); mode {
+	case "":
+		return DedupeModeOff, nil
+	case DedupeModeOff, DedupeModeReuse, DedupeModeLink:
+		return mode, nil
+	}
+	return "", ErrInvalidDedupeMode
+}
+
+func (m DedupeMode) String() string {
+	return string(m)
+}
+
+// LinkResult завершает новую задачу готовым результатом задачи-источника
+func (t *Task) LinkResult(source *Task) error {
+	if t.Status != TaskStatusPending || source.Status != TaskStatusCompleted {
+		return ErrInvalidTaskStatus
+	}
+	now := time.Now()
+	t.Status = TaskStatusCompleted
+	t.Result = maps.Clone(source.Result)
+	t.DuplicateOf = &source.ID
+	t.Password = nil
+	t.UpdatedAt = now
+	t.CompletedAt = &now
+	return nil
+}
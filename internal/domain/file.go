@@ -2,14 +2,30 @@ package domain
 
 import (
 	"errors"
+	"fmt"
 	"path/filepath"
 	"strings"
+
+	"github.com/google/uuid"
 )
 
 var (
 	ErrUnsupportedFileType = errors.New("unsupported file type")
 )
 
+// MIME типы офисных и текстовых документов
+const (
+	ContentTypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	ContentTypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	ContentTypeODT  = "application/vnd.oasis.opendocument.text"
+	ContentTypeHTML = "text/html"
+	ContentTypeText = "text/plain"
+	ContentTypeEML  = "message/rfc822"
+	ContentTypeXPS  = "application/vnd.ms-xpsdocument"
+	ContentTypeOXPS = "application/oxps"
+	ContentTypeEPUB = "application/epub+zip"
+)
+
 // Поддерживаемые MIME типы
 var supportedContentTypes = map[string]bool{
 	"image/png":       true,
@@ -18,6 +34,30 @@ var supportedContentTypes = map[string]bool{
 	"image/webp":      true,
 	"image/tiff":      true,
 	"application/pdf": true,
+	ContentTypeDOCX:   true,
+	ContentTypeXLSX:   true,
+	ContentTypeODT:    true,
+	ContentTypeHTML:   true,
+	ContentTypeText:   true,
+	ContentTypeEML:    true,
+	ContentTypeXPS:    true,
+	ContentTypeOXPS:   true,
+	ContentTypeEPUB:   true,
+}
+
+// Документы со страницами, которые конвертер рендерит в изображения: MIME тип -> расширение файла.
+// Конвертер определяет формат по расширению, поэтому документ сохраняется на диск с ним
+var documentExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	ContentTypeDOCX:   ".docx",
+	ContentTypeXLSX:   ".xlsx",
+	ContentTypeODT:    ".odt",
+	ContentTypeHTML:   ".html",
+	ContentTypeText:   ".txt",
+	ContentTypeEML:    ".eml",
+	ContentTypeXPS:    ".xps",
+	ContentTypeOXPS:   ".oxps",
+	ContentTypeEPUB:   ".epub",
 }
 
 // Маппинг расширений на MIME типы
@@ -29,6 +69,16 @@ var extToContentType = map[string]string{
 	".tiff": "image/tiff",
 	".tif":  "image/tiff",
 	".pdf":  "application/pdf",
+	".docx": ContentTypeDOCX,
+	".xlsx": ContentTypeXLSX,
+	".odt":  ContentTypeODT,
+	".html": ContentTypeHTML,
+	".htm":  ContentTypeHTML,
+	".txt":  ContentTypeText,
+	".eml":  ContentTypeEML,
+	".xps":  ContentTypeXPS,
+	".oxps": ContentTypeOXPS,
+	".epub": ContentTypeEPUB,
 }
 
 // ValidateContentType проверяет поддерживается ли тип файла
@@ -65,4 +115,27 @@ func IsPDF(contentType string) bool {
 	ct := strings.Split(contentType, ";")[0]
 	ct = strings.TrimSpace(strings.ToLower(ct))
 	return ct == "application/pdf"
-}
\ No newline at end of file
+}
+
+// IsDocument проверяет, является ли файл документом со страницами (PDF, офисные, HTML,
+// TXT, EML, XPS, EPUB), который перед отправкой в модель конвертируется в изображения и текст
+func IsDocument(contentType string) bool {
+	_, ok := documentExtensions[baseContentType(contentType)]
+	return ok
+}
+
+// DocumentExtension возвращает расширение файла документа, по которому конвертер определяет формат
+func DocumentExtension(contentType string) string {
+	return documentExtensions[baseContentType(contentType)]
+}
+
+// baseContentType возвращает MIME тип без параметров в нижнем регистре
+func baseContentType(contentType string) string {
+	ct := strings.Split(contentType, ";")[0]
+	return strings.TrimSpace(strings.ToLower(ct))
+}
+
+// PageImageKey возвращает ключ изображения страницы, отправленного в модель
+func PageImageKey(taskID uuid.UUID, page int) string {
+	return fmt.Sprintf("pages/%s/%d.png", taskID, page)
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "errors"
+
+var ErrInvalidInputMode = errors.New("invalid input mode")
+
+// InputMode что отправляется в модель для PDF документа
+type InputMode string
+
+const (
+	InputModeImage  InputMode = "image"  // Только изображения страниц
+	InputModeText   InputMode = "text"   // Только текстовый слой PDF, без изображений
+	InputModeHybrid InputMode = "hybrid" // Изображения страниц и текстовый слой как дополнительный контекст
+)
+
+// ParseInputMode разбирает режим входных данных; пустая строка — режим не задан
+// и берётся из шаблона документа или настроек воркера
+func ParseInputMode(s string) (InputMode, error) {
+	switch mode := InputMode(s); mode {
+	case "", InputModeImage, InputModeText, InputModeHybrid:
+		return mode, nil
+	}
+	return "", ErrInvalidInputMode
+}
+
+func (m InputMode) String() string {
+	return string(m)
+}
+
+// UsesImages проверяет, отправляются ли в модель изображения страниц
+func (m InputMode) UsesImages() bool {
+	return m != InputModeText
+}
+
+// UsesText проверяет, отправляется ли в модель текстовый слой
+func (m InputMode) UsesText() bool {
+	return m == InputModeText || m == InputModeHybrid
+}
@@ -1,14 +1,34 @@
 package domain
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
 const (
 	DefaultPageSize = 20
 	MaxPageSize     = 100
 )
 
+var (
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidSortField = errors.New("invalid sort field")
+)
+
 // Pagination параметры пагинации
 type Pagination struct {
 	Page     int `json:"page"`
 	PageSize int `json:"page_size"`
+	// Keyset пагинация: After — курсор последней записи предыдущей страницы (nil для первой)
+	Keyset bool    `json:"-"`
+	After  *Cursor `json:"-"`
+	// Считать общее количество записей; для keyset пагинации только по запросу,
+	// иначе подсчёт на больших таблицах сводит на нет выигрыш от курсора
+	CountTotal bool `json:"-"`
 }
 
 // NewPagination создаёт параметры пагинации с валидацией
@@ -23,11 +43,21 @@ func NewPagination(page, pageSize int) Pagination {
 		pageSize = MaxPageSize
 	}
 	return Pagination{
-		Page:     page,
-		PageSize: pageSize,
+		Page:       page,
+		PageSize:   pageSize,
+		CountTotal: true,
 	}
 }
 
+// NewCursorPagination создаёт параметры keyset пагинации. countTotal — считать общее количество
+func NewCursorPagination(after *Cursor, pageSize int, countTotal bool) Pagination {
+	p := NewPagination(1, pageSize)
+	p.Keyset = true
+	p.After = after
+	p.CountTotal = countTotal
+	return p
+}
+
 // Offset возвращает смещение для SQL запроса
 func (p Pagination) Offset() int {
 	return (p.Page - 1) * p.PageSize
@@ -38,14 +68,109 @@ func (p Pagination) Limit() int {
 	return p.PageSize
 }
 
+// Cursor позиция в отсортированном списке для keyset пагинации
+type Cursor struct {
+	Value string    `json:"v"`  // Значение поля сортировки
+	ID    uuid.UUID `json:"id"` // ID задачи для однозначного порядка
+}
+
+// Encode кодирует курсор в непрозрачную строку
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor декодирует курсор из строки
+func DecodeCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// TaskSortField поле сортировки списка задач
+type TaskSortField string
+
+const (
+	TaskSortByCreatedAt   TaskSortField = "created_at"
+	TaskSortByUpdatedAt   TaskSortField = "updated_at"
+	TaskSortByCompletedAt TaskSortField = "completed_at"
+	TaskSortByFileName    TaskSortField = "file_name"
+	TaskSortByStatus      TaskSortField = "status"
+)
+
+// IsValid проверяет валидность поля сортировки
+func (f TaskSortField) IsValid() bool {
+	switch f {
+	case TaskSortByCreatedAt, TaskSortByUpdatedAt, TaskSortByCompletedAt, TaskSortByFileName, TaskSortByStatus:
+		return true
+	}
+	return false
+}
+
+// TaskSort сортировка списка задач
+type TaskSort struct {
+	Field TaskSortField `json:"field"`
+	Desc  bool          `json:"desc"`
+}
+
+// DefaultTaskSort сортировка по умолчанию — новые задачи первыми
+var DefaultTaskSort = TaskSort{Field: TaskSortByCreatedAt, Desc: true}
+
+// NewTaskSort создаёт сортировку с валидацией. order: asc или desc
+func NewTaskSort(field, order string) (TaskSort, error) {
+	sort := DefaultTaskSort
+	if field != "" {
+		sort.Field = TaskSortField(field)
+		if !sort.Field.IsValid() {
+			return TaskSort{}, ErrInvalidSortField
+		}
+	}
+
+	switch order {
+	case "":
+	case "asc":
+		sort.Desc = false
+	case "desc":
+		sort.Desc = true
+	default:
+		return TaskSort{}, ErrInvalidSortField
+	}
+
+	return sort, nil
+}
+
 // TaskFilter фильтры для списка задач
 type TaskFilter struct {
-	Status *TaskStatus `json:"status,omitempty"`
+	Status        *TaskStatus `json:"status,omitempty"`
+	CreatedFrom   *time.Time  `json:"created_from,omitempty"`
+	CreatedTo     *time.Time  `json:"created_to,omitempty"`
+	CompletedFrom *time.Time  `json:"completed_from,omitempty"`
+	CompletedTo   *time.Time  `json:"completed_to,omitempty"`
+	FileName      string      `json:"file_name,omitempty"` // Подстрока имени файла
+	ContentType   string      `json:"content_type,omitempty"`
+	Template      string      `json:"template,omitempty"`
+	Tenant        string      `json:"tenant,omitempty"`
+	BatchID       *uuid.UUID  `json:"batch_id,omitempty"`
+	ParentID      *uuid.UUID  `json:"parent_id,omitempty"` // Документы, выделенные из файла задачи
+	FileHash      string      `json:"file_hash,omitempty"` // SHA-256 содержимого файла
+	// Полнотекстовый поиск по результату распознавания
+	Search string `json:"search,omitempty"`
+	// Точное совпадение полей результата (result.invoice_number = X)
+	ResultFields map[string]string `json:"result_fields,omitempty"`
+	Sort         TaskSort          `json:"sort"`
 }
 
 // TaskListResult результат запроса списка задач
 type TaskListResult struct {
 	Tasks      []*Task    `json:"tasks"`
-	Total      int        `json:"total"`
+	Total      int        `json:"total"` // Заполняется, если Pagination.CountTotal
 	Pagination Pagination `json:"pagination"`
-}
\ No newline at end of file
+	NextCursor string     `json:"next_cursor,omitempty"` // Курсор следующей страницы (keyset)
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []Cursor{
+		{Value: "2024-05-01T10:00:00.123456Z", ID: uuid.New()},
+		{Value: "", ID: uuid.New()},
+		{Value: "счёт №1/2.pdf", ID: uuid.New()},
+	}
+
+	for _, cursor := range tests {
+		encoded := cursor.Encode()
+		decoded, err := DecodeCursor(encoded)
+		if err != nil {
+			t.Fatalf("DecodeCursor(%q): %v", encoded, err)
+		}
+		if *decoded != cursor {
+			t.Errorf("DecodeCursor(Encode(%+v)) = %+v", cursor, *decoded)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"empty", ""},
+		{"not base64", "!!!"},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"v":"x","id":"` + uuid.NewString() + `"}`))},
+		{"not json", encode("cursor")},
+		{"missing id", encode(`{"v":"x"}`)},
+		{"nil id", encode(`{"v":"x","id":"` + uuid.Nil.String() + `"}`)},
+		{"invalid id", encode(`{"v":"x","id":"42"}`)},
+		{"wrong value type", encode(`{"v":1,"id":"` + uuid.NewString() + `"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", tt.cursor, err)
+			}
+		})
+	}
+}
+
+func TestNewCursorPagination(t *testing.T) {
+	if p := NewPagination(2, 10); !p.CountTotal || p.Keyset {
+		t.Errorf("NewPagination = %+v, want offset pagination with total", p)
+	}
+
+	after := &Cursor{Value: "x", ID: uuid.New()}
+	p := NewCursorPagination(after, 10, false)
+	if !p.Keyset || p.After != after || p.CountTotal || p.Page != 1 {
+		t.Errorf("NewCursorPagination = %+v, want keyset pagination without total", p)
+	}
+	if p := NewCursorPagination(nil, 10, true); !p.CountTotal {
+		t.Error("NewCursorPagination(countTotal=true) must count total")
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "errors"
+
+var (
+	ErrPasswordRequired    = errors.New("document is password protected")
+	ErrInvalidPassword     = errors.New("invalid document password")
+	ErrPasswordsDisabled   = errors.New("document passwords are not enabled")
+	ErrPasswordUnsupported = errors.New("password protected documents are not supported by this build")
+)
+
+// Коды ошибок задачи, по которым клиент может отреагировать на неудачу обработки
+const (
+	ErrorCodePDFPasswordRequired = "pdf_password_required" // Документ зашифрован, пароль не передан
+	ErrorCodePDFPasswordInvalid  = "pdf_password_invalid"  // Переданный пароль не подошёл
+	// Сборка воркера не умеет разблокировать зашифрованные документы
+	ErrorCodePDFPasswordUnsupported = "pdf_password_unsupported"
+)
+
+// TaskErrorCode возвращает код ошибки задачи для известных ошибок обработки; для остальных — пустой
+func TaskErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrPasswordRequired):
+		return ErrorCodePDFPasswordRequired
+	case errors.Is(err, ErrInvalidPassword):
+		return ErrorCodePDFPasswordInvalid
+	case errors.Is(err, ErrPasswordUnsupported):
+		return ErrorCodePDFPasswordUnsupported
+	case errors.Is(err, ErrUnknownDocumentType):
+		return ErrorCodeUnknownDocumentType
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPromptTemplate = errors.New("invalid prompt template")
+
+// Имена промптов; промпт с именем типа документа (шаблона) заменяет промпт распознавания
+const (
+	PromptRecognize = "recognize" // Извлечение полей по схеме
+	PromptClassify  = "classify"  // Классификация документа по типам
+)
+
+// PromptTemplate промпт модели в формате text/template, хранящийся в БД
+type PromptTemplate struct {
+	Name string // recognize, classify или имя типа документа
+	Body string
+}
+
+// PromptContext переменные промпта распознавания, зависящие от задачи
+type PromptContext struct {
+	DocumentType string           // Тип документа (шаблон или результат классификации)
+	Language     string           // Язык документа и текстовых значений
+	Examples     []map[string]any // Примеры ожидаемого результата
+}
+
+// IsZero проверяет, что переменные не заданы
+func (p PromptContext) IsZero() bool {
+	return p.DocumentType == "" && p.Language == "" && len(p.Examples) == 0
+}
+
+// CacheVersion возвращает версию промпта для задачи и ключа кэша распознавания: к версии
+// шаблона добавляется хэш переменных, от которых зависит текст промпта. Без переменных
+// совпадает с версией шаблона, чтобы не терять ранее закэшированные результаты
+func (p PromptContext) CacheVersion(version string) string {
+	if p.IsZero() {
+		return version
+	}
+	examples, _ := json.Marshal(p.Examples)
+	sum := sha256.Sum256([]byte(strings.Join([]string{p.DocumentType, p.Language, string(examples)}, "\x00")))
+	return version + "+" + hex.EncodeToString(sum[:6])
+}
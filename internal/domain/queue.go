@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Ошибки очереди
+var (
+	ErrQueueTaskNotFound     = errors.New("queue task not found")
+	ErrInvalidQueueTaskState = errors.New("invalid queue task state")
+)
+
+// QueueTaskState состояние задачи в очереди
+type QueueTaskState string
+
+const (
+	QueueTaskStatePending   QueueTaskState = "pending"   // Ожидает обработки
+	QueueTaskStateActive    QueueTaskState = "active"    // Обрабатывается воркером
+	QueueTaskStateScheduled QueueTaskState = "scheduled" // Запланирована на будущее
+	QueueTaskStateRetry     QueueTaskState = "retry"     // Ожидает повторной попытки
+	QueueTaskStateArchived  QueueTaskState = "archived"  // Исчерпала попытки (dead letter)
+	QueueTaskStateCompleted QueueTaskState = "completed" // Завершена
+)
+
+// IsValid проверяет валидность состояния
+func (s QueueTaskState) IsValid() bool {
+	switch s {
+	case QueueTaskStatePending, QueueTaskStateActive, QueueTaskStateScheduled,
+		QueueTaskStateRetry, QueueTaskStateArchived, QueueTaskStateCompleted:
+		return true
+	}
+	return false
+}
+
+func (s QueueTaskState) String() string {
+	return string(s)
+}
+
+// QueueTask задача в очереди
+type QueueTask struct {
+	ID            string         `json:"id"`      // ID задачи в очереди
+	TaskID        string         `json:"task_id"` // ID задачи docrecognizer из payload
+	Type          string         `json:"type"`
+	Queue         string         `json:"queue"`
+	State         QueueTaskState `json:"state"`
+	MaxRetry      int            `json:"max_retry"`
+	Retried       int            `json:"retried"`
+	LastError     string         `json:"last_error,omitempty"`
+	LastFailedAt  *time.Time     `json:"last_failed_at,omitempty"`
+	NextProcessAt *time.Time     `json:"next_process_at,omitempty"`
+}
+
+// QueueInfo состояние очереди
+type QueueInfo struct {
+	Queue     string        `json:"queue"`
+	Size      int           `json:"size"`
+	Pending   int           `json:"pending"`
+	Active    int           `json:"active"`
+	Scheduled int           `json:"scheduled"`
+	Retry     int           `json:"retry"`
+	Archived  int           `json:"archived"`
+	Completed int           `json:"completed"`
+	Processed int           `json:"processed"` // Обработано за сегодня
+	Failed    int           `json:"failed"`    // Ошибок за сегодня
+	Paused    bool          `json:"paused"`
+	Latency   time.Duration `json:"latency"`
+}
@@ -0,0 +1,156 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidRenderOptions = errors.New("invalid render options")
+	ErrPageOutOfRange       = errors.New("page is out of range")
+	ErrTooManyPages         = errors.New("too many pages selected")
+)
+
+// Ограничения параметров рендеринга
+const (
+	MinRenderDPI = 36
+	MaxRenderDPI = 1200
+)
+
+// lastPage обозначение последней страницы в диапазоне страниц
+const lastPage = "last"
+
+// RenderOptions параметры рендеринга страниц PDF для модели.
+// Нулевые значения берутся из шаблона документа, затем из настроек воркера
+type RenderOptions struct {
+	DPI       int    `json:"dpi,omitempty"`        // Разрешение рендеринга
+	Pages     string `json:"pages,omitempty"`      // Страницы, например "1-3,last"
+	MaxWidth  int    `json:"max_width,omitempty"`  // Максимальная ширина изображения в пикселях
+	MaxHeight int    `json:"max_height,omitempty"` // Максимальная высота изображения в пикселях
+}
+
+// IsZero проверяет, что ни один параметр не задан
+func (o RenderOptions) IsZero() bool {
+	return o == RenderOptions{}
+}
+
+// Validate проверяет параметры без учёта количества страниц документа
+func (o RenderOptions) Validate() error {
+	if o.DPI != 0 && (o.DPI < MinRenderDPI || o.DPI > MaxRenderDPI) {
+		return fmt.Errorf("%w: dpi must be between %d and %d", ErrInvalidRenderOptions, MinRenderDPI, MaxRenderDPI)
+	}
+	if o.MaxWidth < 0 || o.MaxHeight < 0 {
+		return fmt.Errorf("%w: max dimensions must be positive", ErrInvalidRenderOptions)
+	}
+	if o.Pages != "" {
+		// Количество страниц неизвестно до обработки: проверяем только синтаксис
+		if _, err := parsePageRanges(o.Pages, math.MaxInt32); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithDefaults заполняет незаданные параметры значениями по умолчанию
+func (o RenderOptions) WithDefaults(defaults RenderOptions) RenderOptions {
+	if o.DPI == 0 {
+		o.DPI = defaults.DPI
+	}
+	if o.Pages == "" {
+		o.Pages = defaults.Pages
+	}
+	if o.MaxWidth == 0 {
+		o.MaxWidth = defaults.MaxWidth
+	}
+	if o.MaxHeight == 0 {
+		o.MaxHeight = defaults.MaxHeight
+	}
+	return o
+}
+
+// PageDPI возвращает разрешение для страницы размером width x height точек (1/72 дюйма):
+// DPI уменьшается, если изображение не помещается в максимальные размеры
+func (o RenderOptions) PageDPI(width, height float64) float64 {
+	dpi := float64(o.DPI)
+	if o.MaxWidth > 0 && width > 0 {
+		dpi = math.Min(dpi, float64(o.MaxWidth)*72/width)
+	}
+	if o.MaxHeight > 0 && height > 0 {
+		dpi = math.Min(dpi, float64(o.MaxHeight)*72/height)
+	}
+	return dpi
+}
+
+// ParsePageRange разбирает диапазон страниц вида "1-3,5,last" или "2-last"
+// и возвращает отсортированные номера страниц (с 1) без повторов.
+// Пустой диапазон означает все страницы документа
+func ParsePageRange(spec string, pageCount int) ([]int, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = "1-" + lastPage
+	}
+
+	ranges, err := parsePageRanges(spec, pageCount)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []int
+	for _, r := range ranges {
+		for page := r[0]; page <= r[1]; page++ {
+			pages = append(pages, page)
+		}
+	}
+
+	slices.Sort(pages)
+	return slices.Compact(pages), nil
+}
+
+// parsePageRanges разбирает диапазон страниц в список пар [first, last]
+func parsePageRanges(spec string, pageCount int) ([][2]int, error) {
+	var ranges [][2]int
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return nil, fmt.Errorf("%w: empty item in page range %q", ErrInvalidRenderOptions, spec)
+		}
+
+		from, to, isRange := strings.Cut(item, "-")
+		first, err := parsePage(from, pageCount)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parsePage(to, pageCount); err != nil {
+				return nil, err
+			}
+		}
+		if first > last {
+			return nil, fmt.Errorf("%w: invalid page range %q", ErrInvalidRenderOptions, item)
+		}
+
+		ranges = append(ranges, [2]int{first, last})
+	}
+	return ranges, nil
+}
+
+// parsePage разбирает номер страницы или "last"
+func parsePage(value string, pageCount int) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == lastPage {
+		return pageCount, nil
+	}
+
+	page, err := strconv.Atoi(value)
+	if err != nil || page < 1 {
+		return 0, fmt.Errorf("%w: invalid page %q", ErrInvalidRenderOptions, value)
+	}
+	if page > pageCount {
+		return 0, fmt.Errorf("%w: page %d, document has %d pages", ErrPageOutOfRange, page, pageCount)
+	}
+	return page, nil
+}
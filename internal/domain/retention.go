@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRetentionPolicy = errors.New("invalid retention policy")
+	ErrTaskFileDeleted        = errors.New("task file has been deleted by retention policy")
+)
+
+// RetentionAction действие, выполняемое над устаревшей задачей
+type RetentionAction string
+
+const (
+	RetentionActionDeleteFile  RetentionAction = "delete_file"  // Удалить исходный файл, сохранить результат
+	RetentionActionPurgeResult RetentionAction = "purge_result" // Удалить файл и результат, сохранить метаданные задачи
+	RetentionActionDelete      RetentionAction = "delete"       // Удалить файл и задачу целиком
+)
+
+// IsValid проверяет валидность действия
+func (a RetentionAction) IsValid() bool {
+	switch a {
+	case RetentionActionDeleteFile, RetentionActionPurgeResult, RetentionActionDelete:
+		return true
+	}
+	return false
+}
+
+func (a RetentionAction) String() string {
+	return string(a)
+}
+
+// RetentionPolicy политика хранения задач в финальном статусе
+type RetentionPolicy struct {
+	Status TaskStatus
+	MaxAge time.Duration // Возраст считается от завершения задачи
+	Action RetentionAction
+	Tenant string // Арендатор; пустой — политика по умолчанию для всех арендаторов
+	// Арендаторы с собственными политиками для того же статуса, на которых
+	// политика по умолчанию не распространяется. Заполняется ParseRetentionPolicies
+	ExcludeTenants []string
+}
+
+// ParseRetentionPolicy разбирает политику в формате status:age:action[:tenant].
+// Возраст задаётся в формате time.Duration или в днях (30d)
+func ParseRetentionPolicy(s string) (RetentionPolicy, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 && len(parts) != 4 {
+		return RetentionPolicy{}, fmt.Errorf("%w %q: expected status:age:action[:tenant]", ErrInvalidRetentionPolicy, s)
+	}
+
+	status := TaskStatus(parts[0])
+	if !status.IsFinal() {
+		return RetentionPolicy{}, fmt.Errorf("%w %q: status must be completed or failed", ErrInvalidRetentionPolicy, s)
+	}
+
+	maxAge, err := parseRetentionAge(parts[1])
+	if err != nil || maxAge <= 0 {
+		return RetentionPolicy{}, fmt.Errorf("%w %q: invalid age", ErrInvalidRetentionPolicy, s)
+	}
+
+	action := RetentionAction(parts[2])
+	if !action.IsValid() {
+		return RetentionPolicy{}, fmt.Errorf("%w %q: unknown action", ErrInvalidRetentionPolicy, s)
+	}
+
+	policy := RetentionPolicy{Status: status, MaxAge: maxAge, Action: action}
+	if len(parts) == 4 {
+		if parts[3] == "" || ValidateTenant(parts[3]) != nil {
+			return RetentionPolicy{}, fmt.Errorf("%w %q: invalid tenant", ErrInvalidRetentionPolicy, s)
+		}
+		policy.Tenant = parts[3]
+	}
+
+	return policy, nil
+}
+
+// ParseRetentionPolicies разбирает список политик, пропуская пустые элементы.
+// Политики арендатора заменяют для него политики по умолчанию с тем же статусом
+func ParseRetentionPolicies(values []string) ([]RetentionPolicy, error) {
+	policies := make([]RetentionPolicy, 0, len(values))
+	for _, v := range values {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+		policy, err := ParseRetentionPolicy(v)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	for i := range policies {
+		if policies[i].Tenant != "" {
+			continue
+		}
+		for _, p := range policies {
+			if p.Tenant != "" && p.Status == policies[i].Status && !slices.Contains(policies[i].ExcludeTenants, p.Tenant) {
+				policies[i].ExcludeTenants = append(policies[i].ExcludeTenants, p.Tenant)
+			}
+		}
+	}
+
+	return policies, nil
+}
+
+func parseRetentionAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+func (p RetentionPolicy) String() string {
+	if p.Tenant != "" {
+		return fmt.Sprintf("%s:%s:%s:%s", p.Status, p.MaxAge, p.Action, p.Tenant)
+	}
+	return fmt.Sprintf("%s:%s:%s", p.Status, p.MaxAge, p.Action)
+}
+
+// RetentionAuditEntry запись журнала удалений по политикам хранения
+type RetentionAuditEntry struct {
+	TaskID        uuid.UUID
+	Tenant        string
+	Action        RetentionAction
+	Policy        string
+	FileKey       string
+	TaskStatus    TaskStatus
+	TaskCreatedAt time.Time
+	ExecutedAt    time.Time
+}
@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    RetentionPolicy
+		wantErr bool
+	}{
+		{policy: "completed:30d:delete_file", want: RetentionPolicy{Status: TaskStatusCompleted, MaxAge: 30 * 24 * time.Hour, Action: RetentionActionDeleteFile}},
+		{policy: " failed:12h:delete ", want: RetentionPolicy{Status: TaskStatusFailed, MaxAge: 12 * time.Hour, Action: RetentionActionDelete}},
+		{policy: "completed:7d:purge_result:acme-1", want: RetentionPolicy{Status: TaskStatusCompleted, MaxAge: 7 * 24 * time.Hour, Action: RetentionActionPurgeResult, Tenant: "acme-1"}},
+		{policy: "completed:30d", wantErr: true},
+		{policy: "completed:30d:delete:acme:extra", wantErr: true},
+		{policy: "completed:30d:delete:", wantErr: true},
+		{policy: "completed:30d:delete:acme corp", wantErr: true},
+		{policy: "completed:30d:delete:" + strings.Repeat("a", maxTenantLength+1), wantErr: true},
+		{policy: "pending:30d:delete", wantErr: true},
+		{policy: "completed:0d:delete", wantErr: true},
+		{policy: "completed:month:delete", wantErr: true},
+		{policy: "completed:30d:archive", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			got, err := ParseRetentionPolicy(tt.policy)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRetentionPolicy) {
+					t.Fatalf("error = %v, want ErrInvalidRetentionPolicy", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseRetentionPolicy: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRetentionPolicy() = %+v, want %+v", got, tt.want)
+			}
+
+			// String возвращает политику в формате, который снова разбирается
+			again, err := ParseRetentionPolicy(got.String())
+			if err != nil || !reflect.DeepEqual(again, got) {
+				t.Errorf("ParseRetentionPolicy(%q) = %+v, %v", got.String(), again, err)
+			}
+		})
+	}
+}
+
+func TestParseRetentionPoliciesTenantOverride(t *testing.T) {
+	policies, err := ParseRetentionPolicies([]string{
+		"completed:30d:delete_file",
+		"",
+		"failed:7d:delete",
+		"completed:7d:purge_result:acme",
+		"completed:1d:delete_file:globex",
+		"completed:90d:delete:acme",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"completed:720h0m0s:delete_file":       {"acme", "globex"},
+		"failed:168h0m0s:delete":               nil,
+		"completed:168h0m0s:purge_result:acme": nil,
+		"completed:24h0m0s:delete_file:globex": nil,
+		"completed:2160h0m0s:delete:acme":      nil,
+	}
+	if len(policies) != len(want) {
+		t.Fatalf("got %d policies, want %d", len(policies), len(want))
+	}
+	for _, policy := range policies {
+		excluded, ok := want[policy.String()]
+		if !ok {
+			t.Errorf("unexpected policy %s", policy)
+			continue
+		}
+		if !reflect.DeepEqual(policy.ExcludeTenants, excluded) {
+			t.Errorf("%s: ExcludeTenants = %v, want %v", policy, policy.ExcludeTenants, excluded)
+		}
+	}
+}
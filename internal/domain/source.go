@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSourceURL       = errors.New("invalid source URL")
+	ErrForbiddenSourceAddress = errors.New("source address is not allowed")
+	ErrFileTooLarge           = errors.New("file is too large")
+	ErrFileNotFound           = errors.New("file not found")
+	ErrInvalidUploadKey       = errors.New("invalid upload key")
+	ErrFileKeyInUse           = errors.New("file key is already used by another task")
+	ErrInvalidSignature       = errors.New("invalid signature")
+	ErrSignedURLExpired       = errors.New("signed URL expired")
+	ErrDirectURLUnavailable   = errors.New("direct file URL is unavailable")
+
+	// ErrNonRetryable помечает ошибки обработки, которые не исправятся повтором
+	ErrNonRetryable = errors.New("non-retryable error")
+)
+
+// UploadKeyPrefix префикс ключей файлов, загружаемых клиентом напрямую в хранилище
+const UploadKeyPrefix = "uploads/"
+
+// defaultRemoteFileName имя файла, если его не удалось определить по URL
+const defaultRemoteFileName = "document"
+
+// FileInfo метаданные файла в хранилище
+type FileInfo struct {
+	Key         string
+	Size        int64
+	ContentType string
+}
+
+// PresignedUpload ссылка для прямой загрузки файла в хранилище
+type PresignedUpload struct {
+	FileKey   string
+	URL       string
+	Method    string
+	ExpiresAt time.Time
+}
+
+// ValidateSourceURL проверяет URL документа для загрузки воркером
+func ValidateSourceURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, ErrInvalidSourceURL
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, ErrInvalidSourceURL
+	}
+	if u.Hostname() == "" || u.User != nil {
+		return nil, ErrInvalidSourceURL
+	}
+	return u, nil
+}
+
+// FileNameFromURL возвращает имя файла из пути URL
+func FileNameFromURL(u *url.URL) string {
+	name := path.Base(u.Path)
+	if name == "" || name == "." || name == "/" {
+		return defaultRemoteFileName
+	}
+	return name
+}
+
+// IsUploadKey проверяет, что ключ указывает на файл прямой загрузки
+func IsUploadKey(key string) bool {
+	rest, ok := strings.CutPrefix(key, UploadKeyPrefix)
+	return ok && rest != "" && !strings.Contains(key, "..")
+}
@@ -0,0 +1,138 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidSplitMode    = errors.New("invalid split mode")
+	ErrSplitUnsupported    = errors.New("only PDF and other documents can be split, not images")
+	ErrNoDocumentsDetected = errors.New("no documents detected, all pages are blank")
+)
+
+// SplitMode способ разделения одного файла на несколько документов
+type SplitMode string
+
+const (
+	SplitModeBlank    SplitMode = "blank"    // Документы разделены пустыми страницами
+	SplitModeClassify SplitMode = "classify" // Граница документа — смена типа страницы по классификации моделью
+)
+
+// ParseSplitMode разбирает режим разделения; пустая строка — файл не разделяется
+func ParseSplitMode(s string) (SplitMode, error) {
+	switch mode := SplitMode(s); mode {
+	case "", SplitModeBlank, SplitModeClassify:
+		return mode, nil
+	}
+	return "", ErrInvalidSplitMode
+}
+
+func (m SplitMode) String() string {
+	return string(m)
+}
+
+// DocumentSegment документ, обнаруженный в файле: диапазон страниц (с 1, включительно)
+type DocumentSegment struct {
+	FirstPage    int
+	LastPage     int
+	DocumentType string  // Тип документа при разделении по классификации
+	Confidence   float64 // Уверенность классификации первой страницы
+}
+
+// Pages возвращает диапазон страниц сегмента в формате RenderOptions.Pages
+func (s DocumentSegment) Pages() string {
+	if s.FirstPage == s.LastPage {
+		return fmt.Sprintf("%d", s.FirstPage)
+	}
+	return fmt.Sprintf("%d-%d", s.FirstPage, s.LastPage)
+}
+
+// SplitByBlankPages делит страницы на документы по пустым страницам-разделителям.
+// Пустые страницы в документы не входят
+func SplitByBlankPages(blank []bool) []DocumentSegment {
+	var segments []DocumentSegment
+	start := 0
+	for i := 0; i <= len(blank); i++ {
+		if i < len(blank) && !blank[i] {
+			if start == 0 {
+				start = i + 1
+			}
+			continue
+		}
+		if start != 0 {
+			segments = append(segments, DocumentSegment{FirstPage: start, LastPage: i})
+			start = 0
+		}
+	}
+	return segments
+}
+
+// SplitByDocumentType делит страницы на документы по смене типа страницы.
+// Страница без определённого типа считается продолжением предыдущего документа
+func SplitByDocumentType(pages []Classification) []DocumentSegment {
+	var segments []DocumentSegment
+	for i, page := range pages {
+		last := len(segments) - 1
+		if last >= 0 && (page.DocumentType == "" || page.DocumentType == segments[last].DocumentType) {
+			segments[last].LastPage = i + 1
+			continue
+		}
+		segments = append(segments, DocumentSegment{
+			FirstPage:    i + 1,
+			LastPage:     i + 1,
+			DocumentType: page.DocumentType,
+			Confidence:   page.Confidence,
+		})
+	}
+	return segments
+}
+
+// NewChildTask создаёт задачу для документа, обнаруженного в файле родительской задачи.
+// Дочерняя задача ссылается на файл родителя и распознаёт только страницы сегмента
+func (t *Task) NewChildTask(segment DocumentSegment) *Task {
+	now := time.Now()
+
+	render := t.Render
+	render.Pages = segment.Pages()
+
+	parentID := t.ID
+	return &Task{
+		ID:          uuid.New(),
+		Status:      TaskStatusPending,
+		FileKey:     t.FileKey,
+		FileName:    t.FileName,
+		Tenant:      t.Tenant,
+		ContentType: t.ContentType,
+		Schema:      t.Schema,
+		Classify:    t.Classify,
+		Template:    t.Template,
+		Render:      render,
+		InputMode:   t.InputMode,
+		BatchID:     t.BatchID,
+		CacheBypass: t.CacheBypass,
+		Password:    t.Password,
+		ParentID:    &parentID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// SplitResult результат родительской задачи: документы, на которые разделён файл
+func SplitResult(children []*Task) map[string]any {
+	documents := make([]any, 0, len(children))
+	for _, child := range children {
+		document := map[string]any{
+			"task_id": child.ID.String(),
+			"pages":   child.Render.Pages,
+		}
+		if child.DocumentType != "" {
+			document["document_type"] = child.DocumentType
+		}
+		documents = append(documents, document)
+	}
+	return map[string]any{"documents": documents}
+}
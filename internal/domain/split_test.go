@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSplitByBlankPages(t *testing.T) {
+	const (
+		B = true  // Пустая страница
+		P = false // Страница с содержимым
+	)
+
+	tests := []struct {
+		name  string
+		blank []bool
+		want  []DocumentSegment
+	}{
+		{"no pages", nil, nil},
+		{"single page", []bool{P}, []DocumentSegment{{FirstPage: 1, LastPage: 1}}},
+		{"all blank", []bool{B, B, B}, nil},
+		{"no blanks", []bool{P, P, P}, []DocumentSegment{{FirstPage: 1, LastPage: 3}}},
+		{"one separator", []bool{P, P, B, P}, []DocumentSegment{
+			{FirstPage: 1, LastPage: 2},
+			{FirstPage: 4, LastPage: 4},
+		}},
+		{"leading blanks", []bool{B, B, P, P}, []DocumentSegment{{FirstPage: 3, LastPage: 4}}},
+		{"trailing blanks", []bool{P, P, B, B}, []DocumentSegment{{FirstPage: 1, LastPage: 2}}},
+		{"consecutive blanks", []bool{P, B, B, B, P, P}, []DocumentSegment{
+			{FirstPage: 1, LastPage: 1},
+			{FirstPage: 5, LastPage: 6},
+		}},
+		{"blanks everywhere", []bool{B, P, B, P, B}, []DocumentSegment{
+			{FirstPage: 2, LastPage: 2},
+			{FirstPage: 4, LastPage: 4},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitByBlankPages(tt.blank); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitByBlankPages() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitByDocumentType(t *testing.T) {
+	page := func(documentType string, confidence float64) Classification {
+		return Classification{DocumentType: documentType, Confidence: confidence}
+	}
+	unknown := Classification{}
+
+	tests := []struct {
+		name  string
+		pages []Classification
+		want  []DocumentSegment
+	}{
+		{"no pages", nil, nil},
+		{"single document", []Classification{page("invoice", 0.9), page("invoice", 0.8)}, []DocumentSegment{
+			{FirstPage: 1, LastPage: 2, DocumentType: "invoice", Confidence: 0.9},
+		}},
+		{"type change", []Classification{page("invoice", 0.9), page("act", 0.7), page("act", 0.95)}, []DocumentSegment{
+			{FirstPage: 1, LastPage: 1, DocumentType: "invoice", Confidence: 0.9},
+			{FirstPage: 2, LastPage: 3, DocumentType: "act", Confidence: 0.7},
+		}},
+		{"unclassified pages continue document", []Classification{page("invoice", 0.9), unknown, unknown, page("act", 0.8), unknown}, []DocumentSegment{
+			{FirstPage: 1, LastPage: 3, DocumentType: "invoice", Confidence: 0.9},
+			{FirstPage: 4, LastPage: 5, DocumentType: "act", Confidence: 0.8},
+		}},
+		{"leading unclassified page", []Classification{unknown, page("invoice", 0.9)}, []DocumentSegment{
+			{FirstPage: 1, LastPage: 1},
+			{FirstPage: 2, LastPage: 2, DocumentType: "invoice", Confidence: 0.9},
+		}},
+		{"all unclassified", []Classification{unknown, unknown, unknown}, []DocumentSegment{
+			{FirstPage: 1, LastPage: 3},
+		}},
+		{"same type again after other type", []Classification{page("invoice", 0.9), page("act", 0.9), page("invoice", 0.6)}, []DocumentSegment{
+			{FirstPage: 1, LastPage: 1, DocumentType: "invoice", Confidence: 0.9},
+			{FirstPage: 2, LastPage: 2, DocumentType: "act", Confidence: 0.9},
+			{FirstPage: 3, LastPage: 3, DocumentType: "invoice", Confidence: 0.6},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitByDocumentType(tt.pages); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitByDocumentType() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocumentSegmentPages(t *testing.T) {
+	tests := []struct {
+		segment DocumentSegment
+		want    string
+	}{
+		{DocumentSegment{FirstPage: 3, LastPage: 3}, "3"},
+		{DocumentSegment{FirstPage: 2, LastPage: 5}, "2-5"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.segment.Pages(); got != tt.want {
+			t.Errorf("Pages() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestNewChildTask(t *testing.T) {
+	batchID := uuid.New()
+	parent := &Task{
+		ID:          uuid.New(),
+		Status:      TaskStatusProcessing,
+		FileKey:     "documents/file.pdf",
+		FileName:    "file.pdf",
+		ContentType: "application/pdf",
+		Schema:      []string{"total"},
+		Render:      RenderOptions{DPI: 150, Pages: "all"},
+		BatchID:     &batchID,
+		Split:       SplitModeBlank,
+		FileHash:    "hash",
+	}
+
+	child := parent.NewChildTask(DocumentSegment{FirstPage: 2, LastPage: 4})
+
+	if child.ID == parent.ID || child.Status != TaskStatusPending {
+		t.Errorf("child must be a new pending task, got id=%s status=%s", child.ID, child.Status)
+	}
+	if child.ParentID == nil || *child.ParentID != parent.ID {
+		t.Errorf("ParentID = %v, want %s", child.ParentID, parent.ID)
+	}
+	if child.FileKey != parent.FileKey {
+		t.Errorf("FileKey = %q, want parent file %q", child.FileKey, parent.FileKey)
+	}
+	if child.Render.Pages != "2-4" || child.Render.DPI != 150 {
+		t.Errorf("Render = %+v, want parent render with pages 2-4", child.Render)
+	}
+	if parent.Render.Pages != "all" {
+		t.Errorf("parent render changed: %+v", parent.Render)
+	}
+	if child.Split != "" || child.FileHash != "" {
+		t.Errorf("child must not be split again or carry parent hash, got split=%q hash=%q", child.Split, child.FileHash)
+	}
+}
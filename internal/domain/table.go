@@ -0,0 +1,242 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidSchema = errors.New("invalid schema")
+
+// ChecksField ключ результата с проверками итогов таблиц
+const ChecksField = "_checks"
+
+// totalTolerance допустимое расхождение суммы строк и итога документа (округление копеек)
+const totalTolerance = 0.01
+
+// TableField таблица в схеме полей: строки с заданными колонками.
+// В схеме объявляется как "items[description, quantity, price, amount=total_amount]":
+// колонка "amount=total_amount" означает, что сумма колонки amount по всем строкам
+// должна совпасть со скалярным полем total_amount
+type TableField struct {
+	Name    string
+	Columns []string
+	Totals  map[string]string // Колонка -> скалярное поле с итогом документа
+}
+
+// Schema разобранная схема полей: скалярные поля и таблицы
+type Schema struct {
+	Fields []string
+	Tables []TableField
+}
+
+// ParseSchema разбирает схему полей. Элемент с квадратными скобками объявляет таблицу,
+// остальные — скалярные поля
+func ParseSchema(schema []string) (Schema, error) {
+	var parsed Schema
+	names := make(map[string]bool)
+	for _, field := range schema {
+		name, columns, isTable := strings.Cut(field, "[")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return Schema{}, fmt.Errorf("%w: empty field name", ErrInvalidSchema)
+		}
+		if name == ChecksField || strings.ContainsAny(name, "[]") {
+			return Schema{}, fmt.Errorf("%w: invalid field name %q", ErrInvalidSchema, name)
+		}
+		if !isTable {
+			// Повтор скалярного поля не влияет на результат, но поле не может совпадать с таблицей
+			if parsed.Table(name) != nil {
+				return Schema{}, fmt.Errorf("%w: duplicate field %q", ErrInvalidSchema, name)
+			}
+			if !names[name] {
+				parsed.Fields = append(parsed.Fields, name)
+			}
+			names[name] = true
+			continue
+		}
+		if names[name] {
+			return Schema{}, fmt.Errorf("%w: duplicate field %q", ErrInvalidSchema, name)
+		}
+		names[name] = true
+
+		table, err := parseTable(name, columns)
+		if err != nil {
+			return Schema{}, err
+		}
+		parsed.Tables = append(parsed.Tables, table)
+	}
+
+	// Итоги таблиц сверяются со скалярными полями той же схемы
+	for _, table := range parsed.Tables {
+		for column, total := range table.Totals {
+			if !names[total] || parsed.Table(total) != nil {
+				return Schema{}, fmt.Errorf("%w: total of %s.%s refers to unknown field %q", ErrInvalidSchema, table.Name, column, total)
+			}
+		}
+	}
+
+	return parsed, nil
+}
+
+// parseTable разбирает колонки таблицы "col1, col2=total]"
+func parseTable(name, spec string) (TableField, error) {
+	spec, ok := strings.CutSuffix(strings.TrimSpace(spec), "]")
+	if !ok || strings.ContainsAny(spec, "[]") {
+		return TableField{}, fmt.Errorf("%w: table %q must be declared as %s[column, ...]", ErrInvalidSchema, name, name)
+	}
+
+	table := TableField{Name: name}
+	seen := make(map[string]bool)
+	for _, column := range strings.Split(spec, ",") {
+		column, total, hasTotal := strings.Cut(column, "=")
+		column, total = strings.TrimSpace(column), strings.TrimSpace(total)
+		if column == "" || (hasTotal && total == "") {
+			return TableField{}, fmt.Errorf("%w: table %q has an empty column", ErrInvalidSchema, name)
+		}
+		if seen[column] {
+			return TableField{}, fmt.Errorf("%w: table %q has duplicate column %q", ErrInvalidSchema, name, column)
+		}
+		seen[column] = true
+		table.Columns = append(table.Columns, column)
+
+		if hasTotal {
+			if table.Totals == nil {
+				table.Totals = make(map[string]string)
+			}
+			table.Totals[column] = total
+		}
+	}
+	return table, nil
+}
+
+// HasTables проверяет, объявлены ли в схеме таблицы
+func (s Schema) HasTables() bool {
+	return len(s.Tables) > 0
+}
+
+// Table возвращает таблицу по имени или nil
+func (s Schema) Table(name string) *TableField {
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+// Normalize приводит ответ модели к схеме: отсутствующие скалярные поля — null,
+// таблицы — массивы строк ровно с объявленными колонками, пустые строки отбрасываются
+func (s Schema) Normalize(result map[string]any) {
+	for _, field := range s.Fields {
+		if _, ok := result[field]; !ok {
+			result[field] = nil
+		}
+	}
+	for _, table := range s.Tables {
+		result[table.Name] = table.normalizeRows(result[table.Name])
+	}
+}
+
+// normalizeRows оставляет в строках только колонки таблицы
+func (t TableField) normalizeRows(value any) []any {
+	items, _ := value.([]any)
+	rows := make([]any, 0, len(items))
+	for _, item := range items {
+		source, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		row := make(map[string]any, len(t.Columns))
+		empty := true
+		for _, column := range t.Columns {
+			row[column] = source[column]
+			if source[column] != nil && source[column] != "" {
+				empty = false
+			}
+		}
+		if !empty {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// TotalCheck результат сверки суммы колонки таблицы с итогом документа
+type TotalCheck struct {
+	Table         string   `json:"table"`
+	Column        string   `json:"column"`
+	TotalField    string   `json:"total_field"`
+	RowsTotal     float64  `json:"rows_total"`
+	DocumentTotal *float64 `json:"document_total"` // nil, если итог не найден в документе
+	Match         bool     `json:"match"`
+}
+
+// CheckTotals сверяет суммы колонок таблиц с итогами документа, объявленными в схеме
+func (s Schema) CheckTotals(result map[string]any) []TotalCheck {
+	var checks []TotalCheck
+	for _, table := range s.Tables {
+		rows, _ := result[table.Name].([]any)
+		for _, column := range table.Columns {
+			totalField, ok := table.Totals[column]
+			if !ok {
+				continue
+			}
+
+			check := TotalCheck{Table: table.Name, Column: column, TotalField: totalField}
+			for _, row := range rows {
+				if values, ok := row.(map[string]any); ok {
+					if amount, ok := ParseAmount(values[column]); ok {
+						check.RowsTotal += amount
+					}
+				}
+			}
+			check.RowsTotal = math.Round(check.RowsTotal*100) / 100
+
+			if total, ok := ParseAmount(result[totalField]); ok {
+				check.DocumentTotal = &total
+				check.Match = math.Abs(check.RowsTotal-total) <= totalTolerance
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+// ParseAmount разбирает денежную сумму из ответа модели: число или строку
+// вида "1 500,00", "1,500.00", "$1500"
+func ParseAmount(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case string:
+		s := strings.Map(func(r rune) rune {
+			switch {
+			case r >= '0' && r <= '9', r == '.', r == ',', r == '-':
+				return r
+			}
+			return -1
+		}, v)
+		// Точка из сокращения валюты ("руб.") не десятичный разделитель
+		s = strings.TrimRight(s, ".,")
+		if s == "" {
+			return 0, false
+		}
+
+		// Последний разделитель — десятичный, если после него не три цифры
+		// или разделители разные; остальные — разделители разрядов
+		lastDot, lastComma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+		decimal := max(lastDot, lastComma)
+		if decimal >= 0 && ((lastDot >= 0 && lastComma >= 0) || len(s)-decimal-1 != 3) {
+			s = strings.NewReplacer(".", "", ",", "").Replace(s[:decimal]) + "." + s[decimal+1:]
+		} else {
+			s = strings.NewReplacer(".", "", ",", "").Replace(s)
+		}
+
+		amount, err := strconv.ParseFloat(s, 64)
+		return amount, err == nil
+	}
+	return 0, false
+}
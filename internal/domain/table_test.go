@@ -0,0 +1,263 @@
+package domain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  []string
+		want    Schema
+		wantErr bool
+	}{
+		{
+			name:   "scalar fields",
+			schema: []string{"number", " date ", "total"},
+			want:   Schema{Fields: []string{"number", "date", "total"}},
+		},
+		{
+			name:   "duplicate scalar fields",
+			schema: []string{"number", "total", "number"},
+			want:   Schema{Fields: []string{"number", "total"}},
+		},
+		{
+			name:   "table with totals",
+			schema: []string{"total_amount", "items[description, quantity, amount = total_amount]"},
+			want: Schema{
+				Fields: []string{"total_amount"},
+				Tables: []TableField{{
+					Name:    "items",
+					Columns: []string{"description", "quantity", "amount"},
+					Totals:  map[string]string{"amount": "total_amount"},
+				}},
+			},
+		},
+		{
+			name:   "total declared after table",
+			schema: []string{"items[amount=total, vat=total_vat]", "total", "total_vat"},
+			want: Schema{
+				Fields: []string{"total", "total_vat"},
+				Tables: []TableField{{
+					Name:    "items",
+					Columns: []string{"amount", "vat"},
+					Totals:  map[string]string{"amount": "total", "vat": "total_vat"},
+				}},
+			},
+		},
+		{
+			name:   "table without totals",
+			schema: []string{"items[description]"},
+			want:   Schema{Tables: []TableField{{Name: "items", Columns: []string{"description"}}}},
+		},
+		{name: "empty field", schema: []string{"number", " "}, wantErr: true},
+		{name: "empty table name", schema: []string{"[a]"}, wantErr: true},
+		{name: "checks field", schema: []string{ChecksField}, wantErr: true},
+		{name: "closing bracket in name", schema: []string{"items]"}, wantErr: true},
+		{name: "unclosed table", schema: []string{"items[description"}, wantErr: true},
+		{name: "nested brackets", schema: []string{"items[rows[a]]"}, wantErr: true},
+		{name: "text after table", schema: []string{"items[a] b"}, wantErr: true},
+		{name: "empty column", schema: []string{"items[a, ]"}, wantErr: true},
+		{name: "empty total", schema: []string{"items[amount=]"}, wantErr: true},
+		{name: "duplicate column", schema: []string{"items[a, a]"}, wantErr: true},
+		{name: "duplicate table", schema: []string{"items[a]", "items[b]"}, wantErr: true},
+		{name: "table after scalar", schema: []string{"items", "items[a]"}, wantErr: true},
+		{name: "scalar after table", schema: []string{"items[a]", "items"}, wantErr: true},
+		{name: "unknown total field", schema: []string{"items[amount=total]"}, wantErr: true},
+		{name: "total refers to table", schema: []string{"taxes[rate]", "items[amount=taxes]"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSchema(tt.schema)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSchema) {
+					t.Fatalf("error = %v, want ErrInvalidSchema", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSchema: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSchema() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value  any
+		want   float64
+		wantOK bool
+	}{
+		{1500.5, 1500.5, true},
+		{"1500", 1500, true},
+		{"1500.50", 1500.5, true},
+		{"1500,50", 1500.5, true},
+		{"1 500,00", 1500, true},
+		{"1 500 000,25 руб.", 1500000.25, true},
+		{"1,500.00", 1500, true},
+		{"1.500,00", 1500, true},
+		{"1,234,567.89", 1234567.89, true},
+		{"1.234.567", 1234567, true},
+		// Три цифры после единственного разделителя — разделитель разрядов
+		{"12.345", 12345, true},
+		{"12,345", 12345, true},
+		{"12.34", 12.34, true},
+		{"0,5", 0.5, true},
+		{"$1500", 1500, true},
+		{"1500 ₽", 1500, true},
+		{"-12,50", -12.5, true},
+		{"-1 500,00", -1500, true},
+		{"-1,500.00", -1500, true},
+		{-42.0, -42, true},
+		{"", 0, false},
+		{"n/a", 0, false},
+		{".", 0, false},
+		{"12-50", 0, false},
+		{nil, 0, false},
+		{true, 0, false},
+		{42, 0, false}, // Числа из JSON всегда float64
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseAmount(tt.value)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("ParseAmount(%#v) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestSchemaNormalize(t *testing.T) {
+	schema, err := ParseSchema([]string{"number", "total", "items[description, amount=total]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := map[string]any{
+		"number": "42",
+		"extra":  "kept",
+		"items": []any{
+			map[string]any{"description": "Работы", "amount": "100", "vat": "20"},
+			map[string]any{"description": "", "amount": nil},
+			"not a row",
+			map[string]any{"amount": 50.0},
+		},
+	}
+	schema.Normalize(result)
+
+	want := map[string]any{
+		"number": "42",
+		"total":  nil,
+		"extra":  "kept",
+		"items": []any{
+			map[string]any{"description": "Работы", "amount": "100"},
+			map[string]any{"description": nil, "amount": 50.0},
+		},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Normalize() = %#v, want %#v", result, want)
+	}
+
+	// Таблица, которую модель не вернула или вернула не массивом, становится пустым массивом
+	for _, items := range []any{nil, "none", map[string]any{"amount": 1.0}} {
+		result := map[string]any{"items": items}
+		schema.Normalize(result)
+		if rows, ok := result["items"].([]any); !ok || len(rows) != 0 {
+			t.Errorf("Normalize(items=%#v) = %#v, want empty array", items, result["items"])
+		}
+	}
+}
+
+func TestSchemaCheckTotals(t *testing.T) {
+	schema, err := ParseSchema([]string{"total", "total_vat", "items[description, amount=total, vat=total_vat]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := func(amounts ...any) []any {
+		items := make([]any, len(amounts))
+		for i, amount := range amounts {
+			items[i] = map[string]any{"description": "row", "amount": amount}
+		}
+		return items
+	}
+	float := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name   string
+		result map[string]any
+		want   TotalCheck
+	}{
+		{
+			name:   "match",
+			result: map[string]any{"items": rows("1 500,00", 250.5), "total": "1 750,50"},
+			want:   TotalCheck{RowsTotal: 1750.5, DocumentTotal: float(1750.5), Match: true},
+		},
+		{
+			name:   "rounding within tolerance",
+			result: map[string]any{"items": rows(0.1, 0.2), "total": 0.3},
+			want:   TotalCheck{RowsTotal: 0.3, DocumentTotal: float(0.3), Match: true},
+		},
+		{
+			name:   "negative rows",
+			result: map[string]any{"items": rows("100,00", "-12,50"), "total": 87.5},
+			want:   TotalCheck{RowsTotal: 87.5, DocumentTotal: float(87.5), Match: true},
+		},
+		{
+			name:   "mismatch",
+			result: map[string]any{"items": rows(100.0, 200.0), "total": "350"},
+			want:   TotalCheck{RowsTotal: 300, DocumentTotal: float(350)},
+		},
+		{
+			name:   "unparsable amounts are skipped",
+			result: map[string]any{"items": rows(100.0, "n/a", nil), "total": 100.0},
+			want:   TotalCheck{RowsTotal: 100, DocumentTotal: float(100), Match: true},
+		},
+		{
+			name:   "total not found",
+			result: map[string]any{"items": rows(100.0), "total": nil},
+			want:   TotalCheck{RowsTotal: 100},
+		},
+		{
+			name:   "no rows",
+			result: map[string]any{"total": 0.0},
+			want:   TotalCheck{DocumentTotal: float(0), Match: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := schema.CheckTotals(tt.result)
+			if len(checks) != 2 {
+				t.Fatalf("got %d checks, want 2 (amount and vat)", len(checks))
+			}
+
+			want := tt.want
+			want.Table, want.Column, want.TotalField = "items", "amount", "total"
+			if !reflect.DeepEqual(checks[0], want) {
+				t.Errorf("amount check = %+v, want %+v", checks[0], want)
+			}
+
+			// Итога НДС нет в результате: сверка не проходит
+			vat := checks[1]
+			if vat.Column != "vat" || vat.TotalField != "total_vat" || vat.DocumentTotal != nil || vat.Match {
+				t.Errorf("vat check = %+v, want unmatched check without document total", vat)
+			}
+		})
+	}
+}
+
+func TestSchemaCheckTotalsWithoutTables(t *testing.T) {
+	schema, err := ParseSchema([]string{"total", "items[description]"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checks := schema.CheckTotals(map[string]any{"total": 1.0}); checks != nil {
+		t.Errorf("CheckTotals() = %+v, want nil", checks)
+	}
+}
@@ -17,17 +17,38 @@ var (
 
 // Task представляет задачу на распознавание документа
 type Task struct {
-	ID           uuid.UUID         `json:"id"`
-	Status       TaskStatus        `json:"status"`
-	FileKey      string            `json:"file_key"`       // Ключ файла в S3
-	FileName     string            `json:"file_name"`      // Оригинальное имя файла
-	ContentType  string            `json:"content_type"`   // MIME тип (image/png, application/pdf)
-	Schema       []string          `json:"schema"`         // Поля для извлечения
-	Result       map[string]any    `json:"result,omitempty"` // Результат распознавания
-	Error        string            `json:"error,omitempty"`  // Текст ошибки (если failed)
-	CreatedAt    time.Time         `json:"created_at"`
-	UpdatedAt    time.Time         `json:"updated_at"`
-	CompletedAt  *time.Time        `json:"completed_at,omitempty"`
+	ID                       uuid.UUID      `json:"id"`
+	Status                   TaskStatus     `json:"status"`
+	FileKey                  string         `json:"file_key"`                            // Ключ файла в S3
+	FileName                 string         `json:"file_name"`                           // Оригинальное имя файла
+	Tenant                   string         `json:"tenant,omitempty"`                    // Арендатор, которому принадлежит документ
+	ContentType              string         `json:"content_type"`                        // MIME тип (image/png, application/pdf)
+	Schema                   []string       `json:"schema"`                              // Поля для извлечения
+	Classify                 bool           `json:"classify,omitempty"`                  // Определить тип документа перед извлечением
+	DocumentType             string         `json:"document_type,omitempty"`             // Тип документа, предсказанный моделью
+	ClassificationConfidence float64        `json:"classification_confidence,omitempty"` // Уверенность классификации (0..1)
+	Template                 string         `json:"template,omitempty"`                  // Шаблон (тип) документа
+	Render                   RenderOptions  `json:"render,omitempty"`                    // Параметры рендеринга PDF
+	InputMode                InputMode      `json:"input_mode,omitempty"`                // Входные данные модели для PDF: image, text, hybrid
+	BatchID                  *uuid.UUID     `json:"batch_id,omitempty"`                  // Пакет загрузки
+	Split                    SplitMode      `json:"split,omitempty"`                     // Разделить файл на несколько документов
+	ParentID                 *uuid.UUID     `json:"parent_id,omitempty"`                 // Задача, из файла которой выделен документ
+	FileHash                 string         `json:"file_hash,omitempty"`                 // SHA-256 содержимого файла (hex)
+	DuplicateOf              *uuid.UUID     `json:"duplicate_of,omitempty"`              // Задача, чей результат переиспользован
+	CacheBypass              bool           `json:"cache_bypass,omitempty"`              // Не брать результат из кэша распознавания
+	CacheStatus              CacheStatus    `json:"cache_status,omitempty"`              // hit, miss или bypass
+	PromptVersion            string         `json:"prompt_version,omitempty"`            // Версия промпта, с которым распознан документ
+	SourceURL                string         `json:"source_url,omitempty"`                // URL, с которого воркер скачивает документ
+	PageKeys                 []string       `json:"page_keys,omitempty"`                 // Ключи изображений, отправленных в модель (по страницам)
+	Result                   map[string]any `json:"result,omitempty"`                    // Результат распознавания
+	Error                    string         `json:"error,omitempty"`                     // Текст ошибки (если failed)
+	ErrorCode                string         `json:"error_code,omitempty"`                // Код ошибки для клиента, например pdf_password_required
+	Password                 []byte         `json:"-"`                                   // Пароль документа, зашифрованный SecretSealer
+	CreatedAt                time.Time      `json:"created_at"`
+	UpdatedAt                time.Time      `json:"updated_at"`
+	CompletedAt              *time.Time     `json:"completed_at,omitempty"`
+	FileDeletedAt            *time.Time     `json:"file_deleted_at,omitempty"`  // Исходный файл удалён по политике хранения
+	ResultPurgedAt           *time.Time     `json:"result_purged_at,omitempty"` // Результат удалён по политике хранения
 }
 
 // NewTask создаёт новую задачу
@@ -35,9 +56,6 @@ func NewTask(fileKey, fileName, contentType string, schema []string) (*Task, err
 	if fileKey == "" {
 		return nil, ErrEmptyFileKey
 	}
-	if len(schema) == 0 {
-		return nil, ErrEmptySchema
-	}
 
 	now := time.Now()
 
@@ -53,16 +71,97 @@ func NewTask(fileKey, fileName, contentType string, schema []string) (*Task, err
 	}, nil
 }
 
+// NewRemoteTask создаёт задачу для документа, который воркер скачает по URL.
+// Ключ файла и тип содержимого заполняются после загрузки
+func NewRemoteTask(sourceURL, fileName string, schema []string) (*Task, error) {
+	if sourceURL == "" {
+		return nil, ErrInvalidSourceURL
+	}
+
+	now := time.Now()
+
+	return &Task{
+		ID:        uuid.New(),
+		Status:    TaskStatusPending,
+		FileName:  fileName,
+		Schema:    schema,
+		SourceURL: sourceURL,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// Validate проверяет задачу перед сохранением: схема полей обязательна,
+// если её не определит классификация
+func (t *Task) Validate() error {
+	if len(t.Schema) == 0 && !t.Classify {
+		return ErrEmptySchema
+	}
+	if err := ValidateTenant(t.Tenant); err != nil {
+		return err
+	}
+	if _, err := ParseSchema(t.Schema); err != nil {
+		return err
+	}
+	// Тип задачи по URL станет известен только после скачивания
+	if t.Split != "" && t.ContentType != "" && !IsDocument(t.ContentType) {
+		return ErrSplitUnsupported
+	}
+	return nil
+}
+
+// HasFile проверяет, загружен ли документ задачи в хранилище
+func (t *Task) HasFile() bool {
+	return t.FileKey != ""
+}
+
+// AttachFile сохраняет сведения о скачанном по URL документе
+func (t *Task) AttachFile(fileKey, contentType, fileHash string) error {
+	if fileKey == "" {
+		return ErrEmptyFileKey
+	}
+	t.FileKey = fileKey
+	t.ContentType = contentType
+	t.FileHash = fileHash
+	t.UpdatedAt = time.Now()
+	return nil
+}
+
+// StoredKeys возвращает ключи всех файлов задачи в хранилище:
+// исходный документ и отрендеренные изображения страниц.
+// Исходный файл разделённого документа общий для родительской и дочерних задач,
+// перед удалением нужно проверить, что он не используется другими задачами
+func (t *Task) StoredKeys() []string {
+	keys := make([]string, 0, len(t.PageKeys)+1)
+	if t.FileKey != "" {
+		keys = append(keys, t.FileKey)
+	}
+	for _, key := range t.PageKeys {
+		if key != t.FileKey {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
 // MarkProcessing переводит задачу в статус "в обработке"
 func (t *Task) MarkProcessing() error {
 	if t.Status != TaskStatusPending {
 		return ErrInvalidTaskStatus
 	}
 	t.Status = TaskStatusProcessing
-	t.UpdatedAt = time.Now()
+	t.StartAttempt()
 	return nil
 }
 
+// StartAttempt сбрасывает ошибку и её код от предыдущей попытки: они не относятся к новой.
+// Вызывается и при возобновлении задачи, оставшейся в статусе "в обработке"
+func (t *Task) StartAttempt() {
+	t.Error = ""
+	t.ErrorCode = ""
+	t.UpdatedAt = time.Now()
+}
+
 // MarkCompleted переводит задачу в статус "завершена"
 func (t *Task) MarkCompleted(result map[string]any) error {
 	if t.Status != TaskStatusProcessing {
@@ -71,6 +170,9 @@ func (t *Task) MarkCompleted(result map[string]any) error {
 	now := time.Now()
 	t.Status = TaskStatusCompleted
 	t.Result = result
+	t.Error = ""
+	t.ErrorCode = ""
+	t.Password = nil // Пароль больше не нужен и не хранится дольше обработки
 	t.UpdatedAt = now
 	t.CompletedAt = &now
 	return nil
@@ -89,7 +191,32 @@ func (t *Task) MarkFailed(errMsg string) error {
 	return nil
 }
 
+// MarkRetrying возвращает задачу в ожидание после неудачной попытки,
+// сохраняя текст ошибки до следующей попытки
+func (t *Task) MarkRetrying(errMsg string) error {
+	if t.Status != TaskStatusProcessing {
+		return ErrInvalidTaskStatus
+	}
+	t.Status = TaskStatusPending
+	t.Error = errMsg
+	t.UpdatedAt = time.Now()
+	return nil
+}
+
+// Requeue возвращает неудачную задачу в ожидание для повторной обработки
+func (t *Task) Requeue() error {
+	if !t.CanRetry() {
+		return ErrInvalidTaskStatus
+	}
+	t.Status = TaskStatusPending
+	t.Error = ""
+	t.ErrorCode = ""
+	t.UpdatedAt = time.Now()
+	t.CompletedAt = nil
+	return nil
+}
+
 // CanRetry проверяет, можно ли повторить задачу
 func (t *Task) CanRetry() bool {
 	return t.Status == TaskStatusFailed
-}
\ No newline at end of file
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "testing"
+
+func TestTaskRetryClearsPreviousError(t *testing.T) {
+	stale := func(task *Task) {
+		task.Error = "stale"
+		task.ErrorCode = ErrorCodePDFPasswordRequired
+	}
+
+	task, err := NewTask("documents/file.pdf", "file.pdf", "application/pdf", []string{"total"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := task.MarkProcessing(); err != nil {
+		t.Fatal(err)
+	}
+	stale(task)
+	if err := task.MarkRetrying("failed to prepare document: password required"); err != nil {
+		t.Fatal(err)
+	}
+	if task.Error == "" || task.ErrorCode == "" {
+		t.Fatal("retrying task must keep the error until the next attempt")
+	}
+
+	if err := task.MarkProcessing(); err != nil {
+		t.Fatal(err)
+	}
+	if task.Error != "" || task.ErrorCode != "" {
+		t.Errorf("new attempt kept previous error %q (%s)", task.Error, task.ErrorCode)
+	}
+
+	// Попытка, прерванная без сохранения статуса, возобновляется из processing
+	stale(task)
+	task.StartAttempt()
+	if task.Error != "" || task.ErrorCode != "" {
+		t.Errorf("resumed attempt kept previous error %q (%s)", task.Error, task.ErrorCode)
+	}
+
+	stale(task)
+	if err := task.MarkCompleted(map[string]any{"total": 1.0}); err != nil {
+		t.Fatal(err)
+	}
+	if task.Error != "" || task.ErrorCode != "" {
+		t.Errorf("completed task kept error %q (%s)", task.Error, task.ErrorCode)
+	}
+}
@@ -0,0 +1,15 @@
+package domain
+
+// Template шаблон (тип) документа с настройками обработки.
+// Задачи ссылаются на шаблон по имени в поле Template
+type Template struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"` // Описание типа документа для классификации
+	Schema      []string `json:"schema,omitempty"`      // Поля для извлечения из документов этого типа
+
+	Language string           `json:"language,omitempty"` // Язык документов для промпта распознавания
+	Examples []map[string]any `json:"examples,omitempty"` // Примеры ожидаемого результата для промпта
+
+	Render    RenderOptions `json:"render"`               // Параметры рендеринга по умолчанию для документов шаблона
+	InputMode InputMode     `json:"input_mode,omitempty"` // Входные данные модели по умолчанию
+}
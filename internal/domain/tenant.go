@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"errors"
+)
+
+var ErrInvalidTenant = errors.New("invalid tenant")
+
+// maxTenantLength максимальная длина идентификатора арендатора
+const maxTenantLength = 64
+
+// ValidateTenant проверяет идентификатор арендатора; пустой — задача без арендатора.
+// Допустимы латинские буквы, цифры, '.', '_' и '-': идентификатор входит в формат политик хранения
+func ValidateTenant(tenant string) error {
+	if len(tenant) > maxTenantLength {
+		return ErrInvalidTenant
+	}
+	for _, r := range tenant {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+		default:
+			return ErrInvalidTenant
+		}
+	}
+	return nil
+}
@@ -2,18 +2,100 @@ package usecase
 
 import (
 	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/plastinin/docrecognizer/internal/domain"
 )
 
 // CreateTaskInput входные данные для создания задачи
 type CreateTaskInput struct {
-	FileName    string    // Имя файла
-	ContentType string    // MIME тип
-	FileSize    int64     // Размер файла
-	FileReader  io.Reader // Содержимое файла
-	Schema      []string  // Поля для извлечения
+	FileName    string               // Имя файла
+	ContentType string               // MIME тип
+	FileSize    int64                // Размер файла (-1, если неизвестен)
+	FileReader  io.Reader            // Содержимое файла
+	File        *UploadedFile        // Уже загруженный файл (потоковая загрузка), FileReader не используется
+	Schema      []string             // Поля для извлечения (может быть пустой при классификации)
+	Classify    bool                 // Определить тип документа среди шаблонов перед извлечением
+	Split       domain.SplitMode     // Разделить файл на несколько документов
+	Template    string               // Шаблон (тип) документа
+	Tenant      string               // Арендатор, которому принадлежит документ
+	Render      domain.RenderOptions // Параметры рендеринга PDF
+	InputMode   domain.InputMode     // Входные данные модели для PDF
+	BatchID     *uuid.UUID           // Пакет загрузки
+	Dedupe      domain.DedupeMode
+	CacheBypass bool   // Не брать результат из кэша распознавания
+	Password    string // Пароль зашифрованного PDF; хранится зашифрованным
+}
+
+// UploadedFile файл, загруженный в хранилище до создания задачи
+type UploadedFile struct {
+	Key  string // Ключ в хранилище
+	Size int64  // Размер содержимого
+	Hash string // SHA-256 содержимого (hex)
+}
+
+// CreateTaskFromURLInput входные данные для создания задачи по URL документа
+type CreateTaskFromURLInput struct {
+	SourceURL   string               // URL, с которого воркер скачает документ
+	FileName    string               // Имя файла (по умолчанию из пути URL)
+	Schema      []string             // Поля для извлечения (может быть пустой при классификации)
+	Classify    bool                 // Определить тип документа среди шаблонов перед извлечением
+	Split       domain.SplitMode     // Разделить файл на несколько документов
+	Template    string               // Шаблон (тип) документа
+	Tenant      string               // Арендатор, которому принадлежит документ
+	Render      domain.RenderOptions // Параметры рендеринга PDF
+	InputMode   domain.InputMode     // Входные данные модели для PDF
+	BatchID     *uuid.UUID           // Пакет загрузки
+	CacheBypass bool                 // Не брать результат из кэша распознавания
+	Password    string               // Пароль зашифрованного PDF; хранится зашифрованным
+}
+
+// CreateTaskFromUploadInput входные данные для создания задачи по файлу прямой загрузки
+type CreateTaskFromUploadInput struct {
+	FileKey     string               // Ключ, выданный при создании presigned URL
+	Schema      []string             // Поля для извлечения (может быть пустой при классификации)
+	Classify    bool                 // Определить тип документа среди шаблонов перед извлечением
+	Split       domain.SplitMode     // Разделить файл на несколько документов
+	Template    string               // Шаблон (тип) документа
+	Tenant      string               // Арендатор, которому принадлежит документ
+	Render      domain.RenderOptions // Параметры рендеринга PDF
+	InputMode   domain.InputMode     // Входные данные модели для PDF
+	BatchID     *uuid.UUID           // Пакет загрузки
+	CacheBypass bool                 // Не брать результат из кэша распознавания
+	Password    string               // Пароль зашифрованного PDF; хранится зашифрованным
+}
+
+// UploadOptions ограничения загрузки файлов в обход API
+type UploadOptions struct {
+	MaxSize    int64         // Максимальный размер файла
+	PresignTTL time.Duration // Срок действия presigned URL
+}
+
+// RecognitionOptions настройки обработки задач воркером
+type RecognitionOptions struct {
+	MemoryLimit int64                // Общий лимит памяти задачи на файл и изображения страниц, остальное — на диске
+	TempDir     string               // Каталог временных файлов (пустой — системный)
+	Render      domain.RenderOptions // Параметры рендеринга PDF по умолчанию
+	MaxPages    int                  // Максимум страниц, отправляемых в модель
+	InputMode   domain.InputMode     // Входные данные модели для PDF по умолчанию
+	// Минимальная уверенность классификации, при которой используется схема предсказанного типа
+	MinConfidence float64
+	// Максимум страниц файла, который можно разделить на документы
+	SplitMaxPages int
+	// Язык документов для промпта распознавания по умолчанию
+	Language string
+}
+
+// CreateTaskOutput результат создания задачи
+type CreateTaskOutput struct {
+	Task *domain.Task
+	// Возвращена существующая задача с тем же файлом и схемой (режим reuse)
+	Deduplicated bool
 }
 
 // ProcessTaskInput входные данные для обработки задачи воркером
 type ProcessTaskInput struct {
-	TaskID string
-}
\ No newline at end of file
+	TaskID      uuid.UUID
+	LastAttempt bool // Последняя попытка: при ошибке задача помечается как failed
+}
@@ -0,0 +1,61 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+	"go.uber.org/zap"
+)
+
+// classify определяет тип документа среди шаблонов со схемой полей и переключает
+// извлечение на схему предсказанного типа. При недостаточной уверенности используется
+// схема, переданная при создании задачи; без неё задача завершается ошибкой
+func (uc *RecognitionUseCase) classify(ctx context.Context, task *domain.Task, document documentInput) error {
+	// Тип уже определён на предыдущей попытке
+	if !task.Classify || task.DocumentType != "" {
+		return nil
+	}
+
+	var types []*domain.Template
+	if uc.templates != nil {
+		types = domain.ClassifyTemplates(uc.templates.List())
+	}
+	if len(types) == 0 {
+		if len(task.Schema) > 0 {
+			return nil
+		}
+		return fmt.Errorf("%w: %w", domain.ErrNonRetryable, domain.ErrNoDocumentTypes)
+	}
+
+	ctx, span := tracing.Start(ctx, "llm.ClassifyDocument")
+	classification, err := uc.llmClient.ClassifyDocument(ctx, document.images.Readers(), document.text, types)
+	tracing.RecordError(span, err)
+	span.End()
+	if err != nil {
+		return err
+	}
+
+	template, ok := uc.templates.Get(classification.DocumentType)
+	if ok && classification.Confidence >= uc.options.MinConfidence {
+		task.ApplyClassification(*classification, template)
+		uc.logger.Info("Document classified",
+			zap.String("task_id", task.ID.String()),
+			zap.String("document_type", task.DocumentType),
+			zap.Float64("confidence", task.ClassificationConfidence),
+		)
+		return nil
+	}
+
+	uc.logger.Info("Document type not determined",
+		zap.String("task_id", task.ID.String()),
+		zap.String("predicted_type", classification.DocumentType),
+		zap.Float64("confidence", classification.Confidence),
+		zap.Bool("fallback_schema", len(task.Schema) > 0),
+	)
+	if len(task.Schema) > 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", domain.ErrNonRetryable, domain.ErrUnknownDocumentType)
+}
@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// documentInput документ, подготовленный для модели: изображения страниц и текстовый слой PDF
+type documentInput struct {
+	images pageImages
+	text   string // Пустой, если текстовый слой не используется или отсутствует
+}
+
+// Sum возвращает SHA-256 входных данных модели. Без текстового слоя совпадает
+// с хэшем изображений, чтобы не терять ранее закэшированные результаты
+func (d documentInput) Sum() []byte {
+	if d.text == "" {
+		return d.images.Sum()
+	}
+	h := sha256.New()
+	if len(d.images) > 0 {
+		h.Write(d.images.Sum())
+	}
+	h.Write([]byte("\x00text\x00"))
+	h.Write([]byte(d.text))
+	return h.Sum(nil)
+}
+
+// Close освобождает изображения страниц
+func (d documentInput) Close() {
+	d.images.Close()
+}
+
+// extractText извлекает текстовый слой выбранных страниц (номера с 1).
+// Возвращает пустую строку, если ни на одной странице нет текста
+func extractText(doc PDFDocument, pages []int) (string, error) {
+	var b strings.Builder
+	for _, page := range pages {
+		text, err := doc.PageText(page - 1)
+		if err != nil {
+			return "", err
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n\n")
+		}
+		// Номер страницы помогает модели сопоставить текст с изображениями
+		fmt.Fprintf(&b, "--- Page %d ---\n%s", page, text)
+	}
+	return b.String(), nil
+}
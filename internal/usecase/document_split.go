@@ -0,0 +1,234 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
+	"go.uber.org/zap"
+)
+
+const (
+	// blankPageDPI разрешение рендеринга при поиске пустых страниц: мелкие детали не нужны
+	blankPageDPI = 20
+	// blankInkRatio доля тёмных пикселей, ниже которой страница считается пустой (шум сканера)
+	blankInkRatio = 0.005
+	// splitPreviewDPI максимальное разрешение страниц при классификации для разделения
+	splitPreviewDPI = 100
+)
+
+// split делит файл задачи на документы и создаёт для каждого дочернюю задачу.
+// Дочерние задачи создаются атомарно, поэтому при повторе уже созданные переиспользуются
+func (uc *RecognitionUseCase) split(ctx context.Context, task *domain.Task, file *spooledFile, spool *spooler) ([]*domain.Task, error) {
+	children, err := uc.taskRepo.ListChildren(ctx, task.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list child tasks: %w", err)
+	}
+	if len(children) > 0 {
+		// Дочерние задачи созданы предыдущей попыткой, но могли не попасть в очередь
+		for _, child := range children {
+			if child.Status == domain.TaskStatusPending {
+				uc.enqueue(ctx, child)
+			}
+		}
+		return children, nil
+	}
+
+	detectCtx, span := tracing.Start(ctx, "pdf.DetectDocuments")
+	segments, err := uc.detectDocuments(detectCtx, task, file, spool)
+	tracing.RecordError(span, err)
+	span.End()
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("%w: %w", domain.ErrNonRetryable, domain.ErrNoDocumentsDetected)
+	}
+
+	children = make([]*domain.Task, 0, len(segments))
+	for _, segment := range segments {
+		child := task.NewChildTask(segment)
+		uc.applySegmentType(child, segment)
+		children = append(children, child)
+	}
+
+	if err := uc.taskRepo.CreateChildren(ctx, children); err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		uc.enqueue(ctx, child)
+	}
+
+	uc.logger.Info("Document split into child tasks",
+		zap.String("task_id", task.ID.String()),
+		zap.String("split", task.Split.String()),
+		zap.Int("documents", len(children)),
+	)
+
+	return children, nil
+}
+
+// fileReferences проверяет ссылки задач на файл в хранилище
+type fileReferences interface {
+	FileKeyInUse(ctx context.Context, fileKey string, exceptID uuid.UUID) (bool, error)
+}
+
+// ownedKeys возвращает ключи файлов задачи, которые можно удалить вместе с ней.
+// Исходный файл разделённого документа остаётся, пока он нужен другим задачам
+func ownedKeys(ctx context.Context, refs fileReferences, task *domain.Task) ([]string, error) {
+	keys := task.StoredKeys()
+	if task.FileKey == "" || (task.Split == "" && task.ParentID == nil) {
+		return keys, nil
+	}
+
+	inUse, err := refs.FileKeyInUse(ctx, task.FileKey, task.ID)
+	if err != nil || !inUse {
+		return keys, err
+	}
+
+	owned := keys[:0]
+	for _, key := range keys {
+		if key != task.FileKey {
+			owned = append(owned, key)
+		}
+	}
+	return owned, nil
+}
+
+// applySegmentType переносит тип, определённый при разделении, в дочернюю задачу.
+// Если задача классифицируется, уверенно определённый тип сразу задаёт схему полей
+func (uc *RecognitionUseCase) applySegmentType(child *domain.Task, segment domain.DocumentSegment) {
+	if segment.DocumentType == "" {
+		return
+	}
+	classification := domain.Classification{DocumentType: segment.DocumentType, Confidence: segment.Confidence}
+	if !child.Classify {
+		child.DocumentType = classification.DocumentType
+		child.ClassificationConfidence = classification.Confidence
+		return
+	}
+	if template, ok := uc.templates.Get(segment.DocumentType); ok {
+		child.ApplyClassification(classification, template)
+	}
+}
+
+// detectDocuments находит границы документов в файле задачи
+func (uc *RecognitionUseCase) detectDocuments(ctx context.Context, task *domain.Task, file *spooledFile, spool *spooler) ([]domain.DocumentSegment, error) {
+	if uc.pdfConverter == nil {
+		return nil, fmt.Errorf("document converter not available")
+	}
+
+	doc, err := uc.openDocument(task, file)
+	if err != nil {
+		return nil, err
+	}
+	defer doc.Close()
+
+	pageCount := doc.NumPages()
+	if uc.options.SplitMaxPages > 0 && pageCount > uc.options.SplitMaxPages {
+		return nil, fmt.Errorf("%w: %w: document has %d pages, maximum for split is %d",
+			domain.ErrNonRetryable, domain.ErrTooManyPages, pageCount, uc.options.SplitMaxPages)
+	}
+
+	switch task.Split {
+	case domain.SplitModeBlank:
+		blank := make([]bool, pageCount)
+		for i := range blank {
+			if blank[i], err = isBlankPage(doc, i); err != nil {
+				return nil, fmt.Errorf("failed to render page %d: %w", i+1, err)
+			}
+		}
+		return domain.SplitByBlankPages(blank), nil
+
+	case domain.SplitModeClassify:
+		var types []*domain.Template
+		if uc.templates != nil {
+			types = domain.ClassifyTemplates(uc.templates.List())
+		}
+		if len(types) == 0 {
+			return nil, fmt.Errorf("%w: %w", domain.ErrNonRetryable, domain.ErrNoDocumentTypes)
+		}
+
+		pages := make([]domain.Classification, pageCount)
+		for i := range pages {
+			classification, err := uc.classifyPage(ctx, task, doc, i+1, types, spool)
+			if err != nil {
+				return nil, fmt.Errorf("failed to classify page %d: %w", i+1, err)
+			}
+			// Неуверенно классифицированная страница считается продолжением документа
+			if classification.Confidence >= uc.options.MinConfidence {
+				pages[i] = *classification
+			}
+		}
+		return domain.SplitByDocumentType(pages), nil
+	}
+
+	return nil, fmt.Errorf("%w: %w: %s", domain.ErrNonRetryable, domain.ErrInvalidSplitMode, task.Split)
+}
+
+// classifyPage определяет тип документа по одной странице (номер с 1)
+func (uc *RecognitionUseCase) classifyPage(ctx context.Context, task *domain.Task, doc PDFDocument, page int, types []*domain.Template, spool *spooler) (*domain.Classification, error) {
+	var document documentInput
+	defer document.Close()
+
+	var err error
+	mode := uc.inputMode(task)
+	if mode.UsesText() {
+		if document.text, err = extractText(doc, []int{page}); err != nil {
+			return nil, err
+		}
+	}
+
+	if mode.UsesImages() || document.text == "" {
+		opts := uc.renderOptions(task)
+		opts.DPI = min(opts.DPI, splitPreviewDPI)
+		if document.images, err = uc.renderPages(doc, []int{page}, opts, spool); err != nil {
+			return nil, err
+		}
+	}
+
+	return uc.llmClient.ClassifyDocument(ctx, document.images.Readers(), document.text, types)
+}
+
+// isBlankPage проверяет, что страница (номер с 0) пустая: без текстового слоя
+// и почти без тёмных пикселей на изображении низкого разрешения
+func isBlankPage(doc PDFDocument, page int) (bool, error) {
+	text, err := doc.PageText(page)
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(text) != "" {
+		return false, nil
+	}
+
+	var buf bytes.Buffer
+	if err := doc.RenderPage(page, blankPageDPI, &buf); err != nil {
+		return false, err
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		return false, err
+	}
+
+	bounds := img.Bounds()
+	total := bounds.Dx() * bounds.Dy()
+	if total == 0 {
+		return true, nil
+	}
+
+	ink := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y < 200 {
+				ink++
+			}
+		}
+	}
+	return float64(ink)/float64(total) < blankInkRatio, nil
+}
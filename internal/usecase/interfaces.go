@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/plastinin/docrecognizer/internal/domain"
@@ -11,23 +12,52 @@ import (
 // TaskRepository интерфейс для работы с хранилищем задач
 type TaskRepository interface {
 	Create(ctx context.Context, task *domain.Task) error
+	CreateWithFreeFile(ctx context.Context, task *domain.Task) error   // domain.ErrFileKeyInUse, если на файл ссылается другая задача
+	CreateChildren(ctx context.Context, children []*domain.Task) error // Атомарно, все или ни одной
+	ListChildren(ctx context.Context, parentID uuid.UUID) ([]*domain.Task, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.Task, error)
+	FindCompletedByHash(ctx context.Context, task *domain.Task) (*domain.Task, error) // Тот же файл и параметры, влияющие на результат
 	Update(ctx context.Context, task *domain.Task) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	List(ctx context.Context, filter domain.TaskFilter, pagination domain.Pagination) (*domain.TaskListResult, error)
+	FileKeyInUse(ctx context.Context, fileKey string, exceptID uuid.UUID) (bool, error)
+}
+
+// RetentionRepository интерфейс для применения политик хранения
+type RetentionRepository interface {
+	ListExpired(ctx context.Context, policy domain.RetentionPolicy, before time.Time, limit int) ([]*domain.Task, error)
+	Apply(ctx context.Context, entry *domain.RetentionAuditEntry) (bool, error)
+	FileKeyInUse(ctx context.Context, fileKey string, exceptID uuid.UUID) (bool, error)
 }
 
 // FileStorage интерфейс для работы с файловым хранилищем (S3)
 type FileStorage interface {
 	Upload(ctx context.Context, fileName string, contentType string, reader io.Reader, size int64) (fileKey string, err error)
+	Put(ctx context.Context, fileKey string, contentType string, reader io.Reader, size int64) error
 	Download(ctx context.Context, fileKey string) (io.ReadCloser, error)
 	Delete(ctx context.Context, fileKey string) error
 	GetURL(ctx context.Context, fileKey string) (string, error)
+	PresignUpload(ctx context.Context, fileName string, expiry time.Duration) (*domain.PresignedUpload, error)
+	Stat(ctx context.Context, fileKey string) (*domain.FileInfo, error)
+}
+
+// DocumentFetcher интерфейс для скачивания документов по URL
+type DocumentFetcher interface {
+	Fetch(ctx context.Context, sourceURL string) (io.ReadCloser, *domain.FileInfo, error)
 }
 
 // LLMClient интерфейс для работы с LLM (Ollama)
 type LLMClient interface {
-	RecognizeDocument(ctx context.Context, imageData []byte, contentType string, schema []string) (map[string]any, error)
+	RecognizeDocument(ctx context.Context, images []io.Reader, text string, contentType string, schema []string, prompt domain.PromptContext) (map[string]any, error) // text — текстовый слой документа, если есть
+	ClassifyDocument(ctx context.Context, images []io.Reader, text string, types []*domain.Template) (*domain.Classification, error)
+	Model() string
+	PromptVersion(prompt domain.PromptContext) string // Версия промпта: меняется при изменении инструкций модели
+}
+
+// RecognitionCache интерфейс кэша результатов распознавания
+type RecognitionCache interface {
+	Get(ctx context.Context, key string) (map[string]any, bool, error)
+	Set(ctx context.Context, key string, result map[string]any) error
 }
 
 // TaskQueue интерфейс для работы с очередью задач
@@ -35,7 +65,40 @@ type TaskQueue interface {
 	Enqueue(ctx context.Context, taskID uuid.UUID) error
 }
 
-// PDFConverter интерфейс для конвертации PDF в изображения
+// QueueInspector интерфейс для инспекции и управления очередью распознавания
+type QueueInspector interface {
+	GetQueueInfo(ctx context.Context) (*domain.QueueInfo, error)
+	ListTasks(ctx context.Context, state domain.QueueTaskState, pagination domain.Pagination) ([]*domain.QueueTask, error)
+	GetTask(ctx context.Context, id string) (*domain.QueueTask, error)
+	RunTask(ctx context.Context, id string) error
+	DeleteTask(ctx context.Context, id string) error
+	Pause(ctx context.Context) error
+	Resume(ctx context.Context) error
+}
+
+// PDFConverter интерфейс для конвертации PDF и других документов в изображения страниц
 type PDFConverter interface {
-	ConvertFirstPage(pdfData []byte) ([]byte, error)
-}
\ No newline at end of file
+	// Формат определяется по расширению файла (domain.DocumentExtension); пароль — для зашифрованных PDF
+	Open(path string, password string) (PDFDocument, error)
+}
+
+// PDFDocument открытый документ; страницы рендерятся по одной по запросу
+type PDFDocument interface {
+	NumPages() int
+	PageSize(page int) (width, height float64, err error) // В точках (1/72 дюйма), страницы нумеруются с 0
+	RenderPage(page int, dpi float64, w io.Writer) error  // PNG
+	PageText(page int) (string, error)                    // Текстовый слой; пустой для сканов
+	Close() error
+}
+
+// SecretSealer интерфейс шифрования секретов задачи (паролей документов) для хранения в БД
+type SecretSealer interface {
+	Seal(plaintext []byte) ([]byte, error)
+	Open(sealed []byte) ([]byte, error)
+}
+
+// TemplateRegistry интерфейс настроенных шаблонов документов
+type TemplateRegistry interface {
+	Get(name string) (*domain.Template, bool)
+	List() []*domain.Template // Все шаблоны, отсортированные по имени
+}
@@ -0,0 +1,144 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"go.uber.org/zap"
+)
+
+// QueueUseCase бизнес-логика администрирования очереди распознавания
+type QueueUseCase struct {
+	taskRepo  TaskRepository
+	inspector QueueInspector
+	logger    *zap.Logger
+}
+
+// NewQueueUseCase создаёт новый экземпляр QueueUseCase
+func NewQueueUseCase(
+	taskRepo TaskRepository,
+	inspector QueueInspector,
+	logger *zap.Logger,
+) *QueueUseCase {
+	return &QueueUseCase{
+		taskRepo:  taskRepo,
+		inspector: inspector,
+		logger:    logger,
+	}
+}
+
+// Info возвращает состояние очереди
+func (uc *QueueUseCase) Info(ctx context.Context) (*domain.QueueInfo, error) {
+	return uc.inspector.GetQueueInfo(ctx)
+}
+
+// ListTasks возвращает задачи очереди в указанном состоянии
+func (uc *QueueUseCase) ListTasks(ctx context.Context, state domain.QueueTaskState, pagination domain.Pagination) ([]*domain.QueueTask, error) {
+	if !state.IsValid() {
+		return nil, domain.ErrInvalidQueueTaskState
+	}
+	return uc.inspector.ListTasks(ctx, state, pagination)
+}
+
+// GetTask возвращает задачу очереди по ID
+func (uc *QueueUseCase) GetTask(ctx context.Context, id string) (*domain.QueueTask, error) {
+	return uc.inspector.GetTask(ctx, id)
+}
+
+// Requeue возвращает архивную задачу в очередь на обработку
+func (uc *QueueUseCase) Requeue(ctx context.Context, id string) (*domain.QueueTask, error) {
+	queueTask, err := uc.inspector.GetTask(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if queueTask.State != domain.QueueTaskStateArchived {
+		return nil, domain.ErrInvalidQueueTaskState
+	}
+
+	// Возвращаем задачу в ожидание, иначе воркер пропустит её как завершённую
+	taskID, err := uuid.Parse(queueTask.TaskID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid task ID in payload: %w", err)
+	}
+
+	task, err := uc.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Без исходного файла повторная обработка невозможна
+	if task.FileDeletedAt != nil {
+		return nil, domain.ErrTaskFileDeleted
+	}
+
+	if task.CanRetry() {
+		if err := task.Requeue(); err != nil {
+			return nil, fmt.Errorf("failed to requeue task: %w", err)
+		}
+		if err := uc.taskRepo.Update(ctx, task); err != nil {
+			return nil, fmt.Errorf("failed to update task: %w", err)
+		}
+	}
+
+	if err := uc.inspector.RunTask(ctx, id); err != nil {
+		return nil, err
+	}
+
+	uc.logger.Info("Archived task requeued",
+		zap.String("queue_task_id", id),
+		zap.String("task_id", queueTask.TaskID),
+	)
+
+	queueTask.State = domain.QueueTaskStatePending
+	return queueTask, nil
+}
+
+// DeleteTask удаляет задачу из очереди (задача в БД не затрагивается)
+func (uc *QueueUseCase) DeleteTask(ctx context.Context, id string) error {
+	queueTask, err := uc.inspector.GetTask(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Активную задачу удалить нельзя — она уже обрабатывается воркером
+	if queueTask.State == domain.QueueTaskStateActive {
+		return domain.ErrInvalidQueueTaskState
+	}
+
+	if err := uc.inspector.DeleteTask(ctx, id); err != nil {
+		if errors.Is(err, domain.ErrQueueTaskNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete queue task: %w", err)
+	}
+
+	uc.logger.Info("Queue task deleted",
+		zap.String("queue_task_id", id),
+		zap.String("task_id", queueTask.TaskID),
+		zap.String("state", queueTask.State.String()),
+	)
+
+	return nil
+}
+
+// Pause приостанавливает обработку очереди
+func (uc *QueueUseCase) Pause(ctx context.Context) error {
+	if err := uc.inspector.Pause(ctx); err != nil {
+		return err
+	}
+	uc.logger.Info("Queue paused")
+	return nil
+}
+
+// Resume возобновляет обработку очереди
+func (uc *QueueUseCase) Resume(ctx context.Context) error {
+	if err := uc.inspector.Resume(ctx); err != nil {
+		return err
+	}
+	uc.logger.Info("Queue resumed")
+	return nil
+}
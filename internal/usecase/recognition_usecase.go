@@ -2,21 +2,34 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"strings"
+	"time"
 
-	"github.com/google/uuid"
 	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/pkg/metrics"
+	"github.com/plastinin/docrecognizer/pkg/tracing"
 	"go.uber.org/zap"
 )
 
+// failedTaskSaveTimeout время на сохранение неудачной попытки после отмены контекста задачи
+const failedTaskSaveTimeout = 10 * time.Second
+
 // RecognitionUseCase бизнес-логика распознавания документов
 type RecognitionUseCase struct {
 	taskRepo     TaskRepository
 	fileStorage  FileStorage
+	taskQueue    TaskQueue // Очередь дочерних задач при разделении файла
 	llmClient    LLMClient
 	pdfConverter PDFConverter
+	fetcher      DocumentFetcher
+	cache        RecognitionCache // nil — кэш отключён
+	templates    TemplateRegistry
+	secrets      SecretSealer // nil — пароли документов не расшифровываются
+	options      RecognitionOptions
 	logger       *zap.Logger
 }
 
@@ -24,21 +37,34 @@ type RecognitionUseCase struct {
 func NewRecognitionUseCase(
 	taskRepo TaskRepository,
 	fileStorage FileStorage,
+	taskQueue TaskQueue,
 	llmClient LLMClient,
 	pdfConverter PDFConverter,
+	fetcher DocumentFetcher,
+	cache RecognitionCache,
+	templates TemplateRegistry,
+	secrets SecretSealer,
+	options RecognitionOptions,
 	logger *zap.Logger,
 ) *RecognitionUseCase {
 	return &RecognitionUseCase{
 		taskRepo:     taskRepo,
 		fileStorage:  fileStorage,
+		taskQueue:    taskQueue,
 		llmClient:    llmClient,
 		pdfConverter: pdfConverter,
+		fetcher:      fetcher,
+		cache:        cache,
+		templates:    templates,
+		secrets:      secrets,
+		options:      options,
 		logger:       logger,
 	}
 }
 
 // ProcessTask обрабатывает задачу распознавания
-func (uc *RecognitionUseCase) ProcessTask(ctx context.Context, taskID uuid.UUID) error {
+func (uc *RecognitionUseCase) ProcessTask(ctx context.Context, input ProcessTaskInput) error {
+	taskID := input.TaskID
 	uc.logger.Info("Starting task processing",
 		zap.String("task_id", taskID.String()),
 	)
@@ -58,49 +84,89 @@ func (uc *RecognitionUseCase) ProcessTask(ctx context.Context, taskID uuid.UUID)
 		return nil
 	}
 
-	// Переводим в статус "processing"
-	if err := task.MarkProcessing(); err != nil {
-		return fmt.Errorf("failed to mark task as processing: %w", err)
+	// Переводим в статус "processing". Задача уже в обработке, если предыдущая попытка
+	// прервалась (таймаут, остановка или падение воркера) и asynq доставил её повторно
+	if task.Status == domain.TaskStatusProcessing {
+		uc.logger.Warn("Resuming task left in processing by previous attempt",
+			zap.String("task_id", taskID.String()),
+		)
+		task.StartAttempt()
+	} else {
+		if err := task.MarkProcessing(); err != nil {
+			return fmt.Errorf("failed to mark task as processing: %w", err)
+		}
+		if err := uc.taskRepo.Update(ctx, task); err != nil {
+			return fmt.Errorf("failed to update task status: %w", err)
+		}
 	}
-	if err := uc.taskRepo.Update(ctx, task); err != nil {
-		return fmt.Errorf("failed to update task status: %w", err)
+
+	// Документ, заданный URL, сначала скачиваем в хранилище
+	if !task.HasFile() {
+		if err := uc.fetchSource(ctx, task); err != nil {
+			lastAttempt := input.LastAttempt || errors.Is(err, domain.ErrNonRetryable)
+			uc.markTaskFailed(ctx, task, lastAttempt, "failed to fetch document", err)
+			return fmt.Errorf("failed to fetch document: %w", err)
+		}
 	}
 
-	// Скачиваем файл из S3
-	fileReader, err := uc.fileStorage.Download(ctx, task.FileKey)
+	// Файл и изображения страниц задачи делят один бюджет памяти, остальное — на диске
+	spool := newSpooler(uc.options.MemoryLimit, uc.options.TempDir)
+
+	// Скачиваем файл из S3: небольшие файлы остаются в памяти, крупные и документы — на диске
+	file, err := uc.downloadFile(ctx, task, spool)
 	if err != nil {
-		uc.markTaskFailed(ctx, task, fmt.Sprintf("failed to download file: %v", err))
+		uc.markTaskFailed(ctx, task, input.LastAttempt, "failed to download file", err)
 		return fmt.Errorf("failed to download file: %w", err)
 	}
-	defer fileReader.Close()
+	defer file.Close()
 
-	// Читаем содержимое файла
-	fileData, err := io.ReadAll(fileReader)
-	if err != nil {
-		uc.markTaskFailed(ctx, task, fmt.Sprintf("failed to read file: %v", err))
-		return fmt.Errorf("failed to read file: %w", err)
+	// Для файлов прямой загрузки хэш считается при первой обработке.
+	// Дочерние задачи содержат часть файла, хэш всего файла к ним не относится
+	if task.FileHash == "" && task.ParentID == nil {
+		task.FileHash = file.Hash()
 	}
 
 	uc.logger.Debug("File downloaded from storage",
 		zap.String("task_id", taskID.String()),
-		zap.Int("file_size", len(fileData)),
+		zap.Int64("file_size", file.Size()),
+		zap.Bool("spooled_to_disk", file.Path() != ""),
 		zap.String("content_type", task.ContentType),
 	)
 
-	// Подготавливаем изображение для LLM
-	imageData, err := uc.prepareImageData(fileData, task.ContentType)
+	// Файл с несколькими документами делим на дочерние задачи
+	if task.Split != "" && domain.IsDocument(task.ContentType) {
+		return uc.processSplit(ctx, task, file, spool, input.LastAttempt)
+	}
+
+	// Подготавливаем изображения страниц и текстовый слой для LLM
+	document, err := uc.prepareInput(ctx, task, file, spool)
 	if err != nil {
-		uc.markTaskFailed(ctx, task, fmt.Sprintf("failed to prepare image: %v", err))
-		return fmt.Errorf("failed to prepare image: %w", err)
+		lastAttempt := input.LastAttempt || errors.Is(err, domain.ErrNonRetryable)
+		uc.markTaskFailed(ctx, task, lastAttempt, "failed to prepare document", err)
+		return fmt.Errorf("failed to prepare document: %w", err)
 	}
+	defer document.Close()
 
-	// Отправляем на распознавание в LLM
-	result, err := uc.llmClient.RecognizeDocument(ctx, imageData, "image/png", task.Schema)
+	// Сохраняем изображения, отправленные в модель, чтобы их можно было проверить
+	uc.savePageImages(ctx, task, document.images)
+
+	// Определяем тип документа, если схема полей задаётся классификацией
+	if err := uc.classify(ctx, task, document); err != nil {
+		lastAttempt := input.LastAttempt || errors.Is(err, domain.ErrNonRetryable)
+		uc.markTaskFailed(ctx, task, lastAttempt, "document classification failed", err)
+		return fmt.Errorf("document classification failed: %w", err)
+	}
+
+	// Отправляем на распознавание в LLM (или берём результат из кэша)
+	result, err := uc.recognize(ctx, task, document)
 	if err != nil {
-		uc.markTaskFailed(ctx, task, fmt.Sprintf("LLM recognition failed: %v", err))
+		uc.markTaskFailed(ctx, task, input.LastAttempt, "LLM recognition failed", err)
 		return fmt.Errorf("LLM recognition failed: %w", err)
 	}
 
+	// Сверяем суммы строк таблиц с итогами документа
+	uc.checkTotals(task, result)
+
 	// Успешно завершаем задачу
 	if err := task.MarkCompleted(result); err != nil {
 		return fmt.Errorf("failed to mark task as completed: %w", err)
@@ -108,6 +174,7 @@ func (uc *RecognitionUseCase) ProcessTask(ctx context.Context, taskID uuid.UUID)
 	if err := uc.taskRepo.Update(ctx, task); err != nil {
 		return fmt.Errorf("failed to update task: %w", err)
 	}
+	metrics.ObserveTask(task.Status.String(), time.Since(task.CreatedAt))
 
 	uc.logger.Info("Task completed successfully",
 		zap.String("task_id", taskID.String()),
@@ -117,33 +184,434 @@ func (uc *RecognitionUseCase) ProcessTask(ctx context.Context, taskID uuid.UUID)
 	return nil
 }
 
-// prepareImageData подготавливает изображение для отправки в LLM
-func (uc *RecognitionUseCase) prepareImageData(fileData []byte, contentType string) ([]byte, error) {
-	// Если это PDF — конвертируем первую страницу в изображение
-	if strings.Contains(strings.ToLower(contentType), "pdf") {
-		if uc.pdfConverter == nil {
-			return nil, fmt.Errorf("PDF converter not available")
-		}
-		
-		imageData, err := uc.pdfConverter.ConvertFirstPage(fileData)
+// processSplit разделяет файл задачи на документы. Задача завершается списком
+// дочерних задач с диапазонами страниц, распознавание выполняют дочерние задачи
+func (uc *RecognitionUseCase) processSplit(ctx context.Context, task *domain.Task, file *spooledFile, spool *spooler, lastAttempt bool) error {
+	children, err := uc.split(ctx, task, file, spool)
+	if err != nil {
+		lastAttempt = lastAttempt || errors.Is(err, domain.ErrNonRetryable)
+		uc.markTaskFailed(ctx, task, lastAttempt, "failed to split document", err)
+		return fmt.Errorf("failed to split document: %w", err)
+	}
+
+	if err := task.MarkCompleted(domain.SplitResult(children)); err != nil {
+		return fmt.Errorf("failed to mark task as completed: %w", err)
+	}
+	if err := uc.taskRepo.Update(ctx, task); err != nil {
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+	metrics.ObserveTask(task.Status.String(), time.Since(task.CreatedAt))
+
+	uc.logger.Info("Task split completed",
+		zap.String("task_id", task.ID.String()),
+		zap.Int("documents", len(children)),
+	)
+
+	return nil
+}
+
+// enqueue ставит дочернюю задачу в очередь. Ошибка не возвращается —
+// задача уже создана, её можно поставить в очередь повторно
+func (uc *RecognitionUseCase) enqueue(ctx context.Context, task *domain.Task) {
+	if err := uc.taskQueue.Enqueue(ctx, task.ID); err != nil {
+		uc.logger.Error("Failed to enqueue child task",
+			zap.String("task_id", task.ID.String()),
+			zap.Error(err),
+		)
+	}
+}
+
+// fetchSource скачивает документ по URL задачи и сохраняет его в хранилище.
+// Ошибки, которые не исправятся повтором, оборачиваются в domain.ErrNonRetryable
+func (uc *RecognitionUseCase) fetchSource(ctx context.Context, task *domain.Task) error {
+	reader, info, err := uc.fetcher.Fetch(ctx, task.SourceURL)
+	if err != nil {
+		return nonRetryable(err)
+	}
+	defer reader.Close()
+
+	if err := domain.ValidateContentType(info.ContentType); err != nil {
+		return nonRetryable(fmt.Errorf("%w: %s", err, info.ContentType))
+	}
+
+	hasher := sha256.New()
+	fileKey, err := uc.fileStorage.Upload(ctx, task.FileName, info.ContentType, io.TeeReader(reader, hasher), info.Size)
+	if err != nil {
+		return nonRetryable(err)
+	}
+
+	if err := task.AttachFile(fileKey, info.ContentType, hex.EncodeToString(hasher.Sum(nil))); err != nil {
+		_ = uc.fileStorage.Delete(ctx, fileKey)
+		return err
+	}
+	if err := uc.taskRepo.Update(ctx, task); err != nil {
+		_ = uc.fileStorage.Delete(ctx, fileKey)
+		task.FileKey = ""
+		return fmt.Errorf("failed to update task: %w", err)
+	}
+
+	uc.logger.Info("Document fetched from source URL",
+		zap.String("task_id", task.ID.String()),
+		zap.String("file_key", fileKey),
+		zap.String("content_type", info.ContentType),
+	)
+
+	return nil
+}
+
+// nonRetryable помечает ошибки источника, повтор которых бесполезен
+func nonRetryable(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrForbiddenSourceAddress),
+		errors.Is(err, domain.ErrInvalidSourceURL),
+		errors.Is(err, domain.ErrFileTooLarge),
+		errors.Is(err, domain.ErrUnsupportedFileType):
+		return fmt.Errorf("%w: %w", domain.ErrNonRetryable, err)
+	}
+	return err
+}
+
+// recognize распознаёт документ, используя кэш результатов, если он включён.
+// Версия промпта сохраняется в задаче, чтобы результат можно было воспроизвести:
+// она учитывает не только шаблон, но и язык и примеры, подставленные в промпт
+func (uc *RecognitionUseCase) recognize(ctx context.Context, task *domain.Task, document documentInput) (map[string]any, error) {
+	prompt := uc.promptContext(task)
+	task.PromptVersion = prompt.CacheVersion(uc.llmClient.PromptVersion(prompt))
+
+	if uc.cache == nil {
+		return uc.llmClient.RecognizeDocument(ctx, document.images.Readers(), document.text, "image/png", task.Schema, prompt)
+	}
+
+	key := domain.RecognitionCacheKey(document.Sum(), task.Schema, uc.llmClient.Model(), task.PromptVersion)
+
+	task.CacheStatus = domain.CacheStatusBypass
+	if !task.CacheBypass {
+		task.CacheStatus = domain.CacheStatusMiss
+
+		// Ошибка кэша не должна мешать распознаванию
+		cached, ok, err := uc.cache.Get(ctx, key)
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert PDF: %w", err)
+			uc.logger.Warn("Failed to read recognition cache",
+				zap.String("task_id", task.ID.String()),
+				zap.Error(err),
+			)
+		}
+		if ok {
+			task.CacheStatus = domain.CacheStatusHit
+			metrics.ObserveRecognitionCache(task.CacheStatus.String())
+			uc.logger.Debug("Recognition result taken from cache",
+				zap.String("task_id", task.ID.String()),
+			)
+			return cached, nil
+		}
+	}
+	metrics.ObserveRecognitionCache(task.CacheStatus.String())
+
+	result, err := uc.llmClient.RecognizeDocument(ctx, document.images.Readers(), document.text, "image/png", task.Schema, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.cache.Set(ctx, key, result); err != nil {
+		uc.logger.Warn("Failed to write recognition cache",
+			zap.String("task_id", task.ID.String()),
+			zap.Error(err),
+		)
+	}
+
+	return result, nil
+}
+
+// promptContext собирает переменные промпта распознавания: тип документа,
+// язык и примеры из шаблона задачи
+func (uc *RecognitionUseCase) promptContext(task *domain.Task) domain.PromptContext {
+	prompt := domain.PromptContext{DocumentType: task.DocumentType, Language: uc.options.Language}
+	if prompt.DocumentType == "" {
+		prompt.DocumentType = task.Template
+	}
+
+	if uc.templates != nil && prompt.DocumentType != "" {
+		if template, ok := uc.templates.Get(prompt.DocumentType); ok {
+			if template.Language != "" {
+				prompt.Language = template.Language
+			}
+			prompt.Examples = template.Examples
 		}
-		return imageData, nil
+	}
+	return prompt
+}
+
+// checkTotals добавляет в результат сверку сумм колонок таблиц с итогами документа,
+// если они объявлены в схеме. Расхождение не считается ошибкой распознавания
+func (uc *RecognitionUseCase) checkTotals(task *domain.Task, result map[string]any) {
+	schema, err := domain.ParseSchema(task.Schema)
+	if err != nil {
+		return
 	}
 
+	checks := schema.CheckTotals(result)
+	if len(checks) == 0 {
+		return
+	}
+	result[domain.ChecksField] = checks
+
+	for _, check := range checks {
+		if !check.Match {
+			uc.logger.Warn("Table total does not match document total",
+				zap.String("task_id", task.ID.String()),
+				zap.String("table", check.Table),
+				zap.String("column", check.Column),
+				zap.Float64("rows_total", check.RowsTotal),
+				zap.Any("document_total", check.DocumentTotal),
+			)
+		}
+	}
+}
+
+// savePageImages запоминает изображения страниц, отправленные в модель.
+// Изображения передаются в модель как есть, поэтому для них хранится ссылка на исходный файл
+func (uc *RecognitionUseCase) savePageImages(ctx context.Context, task *domain.Task, images pageImages) {
+	if !domain.IsDocument(task.ContentType) {
+		task.PageKeys = []string{task.FileKey}
+		return
+	}
+
+	keys := make([]string, 0, len(images))
+	for i, image := range images {
+		key := domain.PageImageKey(task.ID, i+1)
+		if err := uc.fileStorage.Put(ctx, key, "image/png", image.Reader(), image.Size()); err != nil {
+			// Не критично для распознавания
+			uc.logger.Warn("Failed to save rendered page image",
+				zap.String("task_id", task.ID.String()),
+				zap.Error(err),
+			)
+			return
+		}
+		keys = append(keys, key)
+	}
+	task.PageKeys = keys
+}
+
+// downloadFile скачивает файл задачи во временное хранилище
+func (uc *RecognitionUseCase) downloadFile(ctx context.Context, task *domain.Task, spool *spooler) (*spooledFile, error) {
+	reader, err := uc.fileStorage.Download(ctx, task.FileKey)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	// Документы открываются конвертером с диска, поэтому в память не читаются
+	var file *spooledFile
+	if domain.IsDocument(task.ContentType) {
+		file, err = spool.spoolDocument(reader, domain.DocumentExtension(task.ContentType))
+	} else {
+		file, err = spool.spool(reader, false)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return file, nil
+}
+
+// prepareInput подготавливает входные данные для LLM: изображения страниц и/или текстовый слой документа
+func (uc *RecognitionUseCase) prepareInput(ctx context.Context, task *domain.Task, file *spooledFile, spool *spooler) (documentInput, error) {
 	// Для изображений возвращаем как есть
-	return fileData, nil
+	if !domain.IsDocument(task.ContentType) {
+		return documentInput{images: pageImages{file}}, nil
+	}
+
+	// PDF и другие документы конвертируем: выбранные страницы в изображения и текст
+	if uc.pdfConverter == nil {
+		return documentInput{}, fmt.Errorf("document converter not available")
+	}
+
+	doc, err := uc.openDocument(task, file)
+	if err != nil {
+		return documentInput{}, err
+	}
+	defer doc.Close()
+
+	opts := uc.renderOptions(task)
+	pages, err := uc.selectPages(doc, opts)
+	if err != nil {
+		return documentInput{}, fmt.Errorf("failed to convert document: %w", err)
+	}
+
+	var document documentInput
+	mode := uc.inputMode(task)
+	if mode.UsesText() {
+		_, span := tracing.Start(ctx, "pdf.ExtractText")
+		document.text, err = extractText(doc, pages)
+		tracing.RecordError(span, err)
+		span.End()
+		if err != nil {
+			return documentInput{}, fmt.Errorf("failed to extract document text: %w", err)
+		}
+
+		// У отсканированного документа нет текстового слоя: распознаём по изображениям
+		if document.text == "" && mode == domain.InputModeText {
+			uc.logger.Info("Document has no text layer, falling back to images",
+				zap.String("task_id", task.ID.String()),
+			)
+			mode = domain.InputModeImage
+		}
+	}
+
+	if mode.UsesImages() {
+		_, span := tracing.Start(ctx, "pdf.RenderPages")
+		document.images, err = uc.renderPages(doc, pages, opts, spool)
+		tracing.RecordError(span, err)
+		span.End()
+		if err != nil {
+			return documentInput{}, fmt.Errorf("failed to convert document: %w", err)
+		}
+	}
+
+	uc.logger.Debug("Document prepared",
+		zap.String("task_id", task.ID.String()),
+		zap.String("pages", opts.Pages),
+		zap.Int("dpi", opts.DPI),
+		zap.String("input_mode", mode.String()),
+		zap.Int("page_count", len(document.images)),
+		zap.Int("text_length", len(document.text)),
+	)
+
+	return document, nil
 }
 
-// markTaskFailed помечает задачу как неудачную
-func (uc *RecognitionUseCase) markTaskFailed(ctx context.Context, task *domain.Task, errMsg string) {
+// openDocument открывает документ задачи конвертером, расшифровывая пароль при необходимости
+func (uc *RecognitionUseCase) openDocument(task *domain.Task, file *spooledFile) (PDFDocument, error) {
+	password, err := uc.openPassword(task)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := uc.pdfConverter.Open(file.Path(), password)
+	if err != nil {
+		// Без верного пароля документ не откроется и при повторе
+		if errors.Is(err, domain.ErrPasswordRequired) || errors.Is(err, domain.ErrInvalidPassword) ||
+			errors.Is(err, domain.ErrPasswordUnsupported) {
+			err = fmt.Errorf("%w: %w", domain.ErrNonRetryable, err)
+		}
+		return nil, fmt.Errorf("failed to convert document: %w", err)
+	}
+	return doc, nil
+}
+
+// openPassword расшифровывает пароль документа задачи. Пароль не логируется
+func (uc *RecognitionUseCase) openPassword(task *domain.Task) (string, error) {
+	if len(task.Password) == 0 {
+		return "", nil
+	}
+	if uc.secrets == nil {
+		return "", fmt.Errorf("%w: %w", domain.ErrNonRetryable, domain.ErrPasswordsDisabled)
+	}
+
+	password, err := uc.secrets.Open(task.Password)
+	if err != nil {
+		return "", fmt.Errorf("%w: failed to decrypt document password: %w", domain.ErrNonRetryable, err)
+	}
+	return string(password), nil
+}
+
+// inputMode возвращает режим входных данных задачи: заданный при создании,
+// затем из шаблона документа, затем настройки воркера
+func (uc *RecognitionUseCase) inputMode(task *domain.Task) domain.InputMode {
+	if task.InputMode != "" {
+		return task.InputMode
+	}
+	if uc.templates != nil && task.Template != "" {
+		if template, ok := uc.templates.Get(task.Template); ok && template.InputMode != "" {
+			return template.InputMode
+		}
+	}
+	if uc.options.InputMode != "" {
+		return uc.options.InputMode
+	}
+	return domain.InputModeImage
+}
+
+// renderOptions возвращает параметры рендеринга задачи: заданные при создании,
+// затем из шаблона документа, затем настройки воркера
+func (uc *RecognitionUseCase) renderOptions(task *domain.Task) domain.RenderOptions {
+	opts := task.Render
+	if uc.templates != nil && task.Template != "" {
+		if template, ok := uc.templates.Get(task.Template); ok {
+			opts = opts.WithDefaults(template.Render)
+		}
+	}
+	return opts.WithDefaults(uc.options.Render)
+}
+
+// selectPages возвращает номера выбранных страниц (с 1) с учётом ограничения на их количество
+func (uc *RecognitionUseCase) selectPages(doc PDFDocument, opts domain.RenderOptions) ([]int, error) {
+	// Диапазон страниц, не подходящий документу, не исправится повтором
+	pages, err := domain.ParsePageRange(opts.Pages, doc.NumPages())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrNonRetryable, err)
+	}
+	if uc.options.MaxPages > 0 && len(pages) > uc.options.MaxPages {
+		return nil, fmt.Errorf("%w: %w: %d pages selected, maximum is %d",
+			domain.ErrNonRetryable, domain.ErrTooManyPages, len(pages), uc.options.MaxPages)
+	}
+	return pages, nil
+}
+
+// renderPages рендерит выбранные страницы документа в PNG по одной. Изображения пишутся
+// во временное хранилище по мере кодирования, не собираясь в памяти сверх бюджета задачи
+func (uc *RecognitionUseCase) renderPages(doc PDFDocument, pages []int, opts domain.RenderOptions, spool *spooler) (images pageImages, err error) {
+	defer func() {
+		if err != nil {
+			images.Close()
+		}
+	}()
+
+	for _, page := range pages {
+		width, height, err := doc.PageSize(page - 1)
+		if err != nil {
+			return images, err
+		}
+		dpi := opts.PageDPI(width, height)
+
+		// Рендеринг ждём на любом пути: вызывающий закрывает документ MuPDF,
+		// пока рендеринг в C ещё мог бы читать его
+		pr, pw := io.Pipe()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			pw.CloseWithError(doc.RenderPage(page-1, dpi, pw))
+		}()
+
+		image, err := spool.spool(pr, false)
+		pr.CloseWithError(err)
+		<-done
+		if err != nil {
+			return images, err
+		}
+		images = append(images, image)
+	}
+
+	return images, nil
+}
+
+// markTaskFailed помечает задачу как неудачную. Код ошибки для клиента определяется
+// по ошибке этой попытки. Если попытка не последняя, задача возвращается в ожидание повтора
+func (uc *RecognitionUseCase) markTaskFailed(ctx context.Context, task *domain.Task, lastAttempt bool, stage string, err error) {
+	errMsg := fmt.Sprintf("%s: %v", stage, err)
+	task.ErrorCode = domain.TaskErrorCode(err)
+
 	uc.logger.Error("Task processing failed",
 		zap.String("task_id", task.ID.String()),
 		zap.String("error", errMsg),
+		zap.Bool("last_attempt", lastAttempt),
 	)
 
-	if err := task.MarkFailed(errMsg); err != nil {
+	mark := task.MarkFailed
+	if !lastAttempt {
+		mark = task.MarkRetrying
+	}
+
+	if err := mark(errMsg); err != nil {
 		uc.logger.Error("Failed to mark task as failed",
 			zap.String("task_id", task.ID.String()),
 			zap.Error(err),
@@ -151,10 +619,20 @@ func (uc *RecognitionUseCase) markTaskFailed(ctx context.Context, task *domain.T
 		return
 	}
 
-	if err := uc.taskRepo.Update(ctx, task); err != nil {
+	// Контекст задачи уже может быть отменён по таймауту или при остановке воркера,
+	// а несохранённая ошибка оставит задачу в статусе processing
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), failedTaskSaveTimeout)
+	defer cancel()
+
+	if err := uc.taskRepo.Update(saveCtx, task); err != nil {
 		uc.logger.Error("Failed to update failed task",
 			zap.String("task_id", task.ID.String()),
 			zap.Error(err),
 		)
+		return
+	}
+
+	if task.Status.IsFinal() {
+		metrics.ObserveTask(task.Status.String(), time.Since(task.CreatedAt))
 	}
 }
\ No newline at end of file
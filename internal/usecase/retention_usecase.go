@@ -0,0 +1,154 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"github.com/plastinin/docrecognizer/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// RetentionUseCase применяет политики хранения задач и файлов
+type RetentionUseCase struct {
+	retentionRepo RetentionRepository
+	fileStorage   FileStorage
+	policies      []domain.RetentionPolicy
+	batchSize     int
+	logger        *zap.Logger
+}
+
+// NewRetentionUseCase создаёт новый экземпляр RetentionUseCase
+func NewRetentionUseCase(
+	retentionRepo RetentionRepository,
+	fileStorage FileStorage,
+	policies []domain.RetentionPolicy,
+	batchSize int,
+	logger *zap.Logger,
+) *RetentionUseCase {
+	return &RetentionUseCase{
+		retentionRepo: retentionRepo,
+		fileStorage:   fileStorage,
+		policies:      policies,
+		batchSize:     batchSize,
+		logger:        logger,
+	}
+}
+
+// Start запускает периодическое применение политик до отмены контекста
+func (uc *RetentionUseCase) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		uc.Run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Run однократно применяет все политики хранения
+func (uc *RetentionUseCase) Run(ctx context.Context) {
+	for _, policy := range uc.policies {
+		processed, err := uc.applyPolicy(ctx, policy)
+		if err != nil {
+			uc.logger.Error("Failed to apply retention policy",
+				zap.String("policy", policy.String()),
+				zap.Error(err),
+			)
+		}
+		if processed > 0 {
+			uc.logger.Info("Retention policy applied",
+				zap.String("policy", policy.String()),
+				zap.Int("tasks", processed),
+			)
+		}
+	}
+}
+
+// applyPolicy обрабатывает устаревшие задачи пачками, пока они не закончатся
+func (uc *RetentionUseCase) applyPolicy(ctx context.Context, policy domain.RetentionPolicy) (int, error) {
+	before := time.Now().Add(-policy.MaxAge)
+	total := 0
+
+	for ctx.Err() == nil {
+		tasks, err := uc.retentionRepo.ListExpired(ctx, policy, before, uc.batchSize)
+		if err != nil {
+			return total, err
+		}
+
+		processed := 0
+		for _, task := range tasks {
+			if uc.applyToTask(ctx, policy, task) {
+				processed++
+			}
+		}
+		total += processed
+
+		// Пачка неполная или не удалось обработать ни одной задачи — выходим,
+		// чтобы не зациклиться на задачах с ошибками
+		if len(tasks) < uc.batchSize || processed == 0 {
+			break
+		}
+	}
+
+	return total, ctx.Err()
+}
+
+// applyToTask удаляет файл задачи и фиксирует действие в БД
+func (uc *RetentionUseCase) applyToTask(ctx context.Context, policy domain.RetentionPolicy, task *domain.Task) bool {
+	// Сначала удаляем файл: при сбое задача останется в выборке и будет обработана повторно
+	if task.FileDeletedAt == nil {
+		keys, err := ownedKeys(ctx, uc.retentionRepo, task)
+		if err != nil {
+			uc.logger.Error("Failed to check file references",
+				zap.String("task_id", task.ID.String()),
+				zap.Error(err),
+			)
+			return false
+		}
+		for _, key := range keys {
+			if err := uc.fileStorage.Delete(ctx, key); err != nil {
+				uc.logger.Error("Failed to delete file by retention policy",
+					zap.String("task_id", task.ID.String()),
+					zap.String("file_key", key),
+					zap.Error(err),
+				)
+				return false
+			}
+		}
+	}
+
+	applied, err := uc.retentionRepo.Apply(ctx, &domain.RetentionAuditEntry{
+		TaskID:        task.ID,
+		Tenant:        task.Tenant,
+		Action:        policy.Action,
+		Policy:        policy.String(),
+		FileKey:       task.FileKey,
+		TaskStatus:    task.Status,
+		TaskCreatedAt: task.CreatedAt,
+		ExecutedAt:    time.Now(),
+	})
+	if err != nil {
+		uc.logger.Error("Failed to apply retention action",
+			zap.String("task_id", task.ID.String()),
+			zap.String("action", policy.Action.String()),
+			zap.Error(err),
+		)
+		return false
+	}
+
+	if applied {
+		metrics.ObserveRetentionAction(policy.Action.String())
+		uc.logger.Debug("Retention action applied",
+			zap.String("task_id", task.ID.String()),
+			zap.String("action", policy.Action.String()),
+		)
+	}
+
+	return true
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+)
+
+// spooler сохраняет содержимое потоков одной задачи на время её обработки.
+// Все потоки задачи делят общий бюджет памяти: пока он не исчерпан, содержимое
+// остаётся в памяти, дальше — во временных файлах. Закрытый файл возвращает память в бюджет.
+// Используется из одной горутины обработки задачи
+type spooler struct {
+	memoryLeft int64 // Оставшийся бюджет памяти задачи
+	tempDir    string
+}
+
+// newSpooler создаёт spooler задачи с бюджетом памяти memoryLimit
+func newSpooler(memoryLimit int64, tempDir string) *spooler {
+	return &spooler{memoryLeft: memoryLimit, tempDir: tempDir}
+}
+
+// spool читает поток целиком, попутно считая SHA-256.
+// toDisk требует сохранить содержимое в файл независимо от размера
+func (s *spooler) spool(reader io.Reader, toDisk bool) (*spooledFile, error) {
+	hasher := sha256.New()
+	reader = io.TeeReader(reader, hasher)
+
+	if !toDisk && s.memoryLeft > 0 {
+		var buf bytes.Buffer
+		n, err := io.CopyN(&buf, reader, s.memoryLeft+1)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if n <= s.memoryLeft {
+			s.memoryLeft -= n
+			return &spooledFile{data: buf.Bytes(), size: n, hasher: hasher, spooler: s}, nil
+		}
+		// Не поместилось в бюджет — дописываем на диск то, что уже прочитано
+		reader = io.MultiReader(&buf, reader)
+	}
+
+	return s.writeTemp(reader, hasher, "")
+}
+
+// spoolDocument сохраняет документ на диск с расширением ext,
+// по которому конвертер определяет формат документа
+func (s *spooler) spoolDocument(reader io.Reader, ext string) (*spooledFile, error) {
+	hasher := sha256.New()
+	return s.writeTemp(io.TeeReader(reader, hasher), hasher, ext)
+}
+
+// writeTemp записывает поток во временный файл
+func (s *spooler) writeTemp(reader io.Reader, hasher hash.Hash, ext string) (*spooledFile, error) {
+	file, err := os.CreateTemp(s.tempDir, "docrecognizer-*"+ext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	size, err := io.Copy(file, reader)
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+
+	return &spooledFile{file: file, size: size, hasher: hasher}, nil
+}
+
+// spooledFile содержимое файла в памяти или во временном файле
+type spooledFile struct {
+	data    []byte
+	file    *os.File
+	size    int64
+	hasher  hash.Hash
+	spooler *spooler // Бюджет, из которого выделена память под data
+}
+
+// Reader возвращает новый поток чтения с начала содержимого
+func (f *spooledFile) Reader() io.Reader {
+	if f.file != nil {
+		return io.NewSectionReader(f.file, 0, f.size)
+	}
+	return bytes.NewReader(f.data)
+}
+
+// Path возвращает путь к временному файлу (пустой, если содержимое в памяти)
+func (f *spooledFile) Path() string {
+	if f.file != nil {
+		return f.file.Name()
+	}
+	return ""
+}
+
+// Size возвращает размер содержимого
+func (f *spooledFile) Size() int64 {
+	return f.size
+}
+
+// Sum возвращает SHA-256 содержимого
+func (f *spooledFile) Sum() []byte {
+	return f.hasher.Sum(nil)
+}
+
+// Hash возвращает SHA-256 содержимого в hex
+func (f *spooledFile) Hash() string {
+	return hex.EncodeToString(f.Sum())
+}
+
+// Close освобождает память и удаляет временный файл
+func (f *spooledFile) Close() error {
+	if f.data != nil {
+		f.spooler.memoryLeft += f.size
+		f.data = nil
+	}
+	if f.file == nil {
+		return nil
+	}
+	file := f.file
+	f.file = nil
+	file.Close()
+	return os.Remove(file.Name())
+}
+
+// pageImages изображения страниц, отправляемые в модель
+type pageImages []*spooledFile
+
+// Readers возвращает потоки чтения изображений
+func (p pageImages) Readers() []io.Reader {
+	readers := make([]io.Reader, len(p))
+	for i, image := range p {
+		readers[i] = image.Reader()
+	}
+	return readers
+}
+
+// Sum возвращает SHA-256 изображений. Для одного изображения совпадает с его хэшем
+func (p pageImages) Sum() []byte {
+	if len(p) == 1 {
+		return p[0].Sum()
+	}
+	h := sha256.New()
+	for _, image := range p {
+		h.Write(image.Sum())
+	}
+	return h.Sum(nil)
+}
+
+// Close освобождает все изображения
+func (p pageImages) Close() {
+	for _, image := range p {
+		image.Close()
+	}
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/plastinin/docrecognizer/internal/domain"
+)
+
+// FileURL возвращает presigned URL исходного файла задачи
+func (uc *TaskUseCase) FileURL(ctx context.Context, id uuid.UUID) (string, error) {
+	task, err := uc.taskWithFile(ctx, id)
+	if err != nil {
+		return "", err
+	}
+
+	url, err := uc.fileStorage.GetURL(ctx, task.FileKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get file URL: %w", err)
+	}
+
+	return url, nil
+}
+
+// OpenFile открывает исходный файл задачи для чтения
+func (uc *TaskUseCase) OpenFile(ctx context.Context, id uuid.UUID) (*domain.Task, io.ReadCloser, error) {
+	task, err := uc.taskWithFile(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := uc.fileStorage.Download(ctx, task.FileKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	return task, reader, nil
+}
+
+// OpenPage открывает изображение страницы (нумерация с 1), отправленное в модель.
+// Возвращает также тип содержимого изображения
+func (uc *TaskUseCase) OpenPage(ctx context.Context, id uuid.UUID, page int) (io.ReadCloser, string, error) {
+	task, err := uc.taskWithFile(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if page < 1 || page > len(task.PageKeys) {
+		return nil, "", domain.ErrFileNotFound
+	}
+	key := task.PageKeys[page-1]
+
+	// Изображения передаются в модель без изменений: страница — это исходный файл
+	contentType := "image/png"
+	if key == task.FileKey {
+		contentType = task.ContentType
+	}
+
+	reader, err := uc.fileStorage.Download(ctx, key)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to download page image: %w", err)
+	}
+
+	return reader, contentType, nil
+}
+
+// taskWithFile возвращает задачу, исходный файл которой доступен в хранилище
+func (uc *TaskUseCase) taskWithFile(ctx context.Context, id uuid.UUID) (*domain.Task, error) {
+	task, err := uc.taskRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if task.FileDeletedAt != nil {
+		return nil, domain.ErrTaskFileDeleted
+	}
+	// Документ по URL ещё не скачан воркером
+	if !task.HasFile() {
+		return nil, domain.ErrFileNotFound
+	}
+
+	return task, nil
+}
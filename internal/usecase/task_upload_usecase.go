@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/plastinin/docrecognizer/internal/domain"
+	"go.uber.org/zap"
+)
+
+// CreateFromURL создаёт задачу, документ для которой воркер скачает по URL
+func (uc *TaskUseCase) CreateFromURL(ctx context.Context, input CreateTaskFromURLInput) (*domain.Task, error) {
+	sourceURL, err := domain.ValidateSourceURL(input.SourceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := domain.FileNameFromURL(sourceURL)
+	if input.FileName != "" {
+		fileName = path.Base(input.FileName)
+	}
+
+	task, err := domain.NewRemoteTask(sourceURL.String(), fileName, input.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+	task.Template = input.Template
+	task.Tenant = input.Tenant
+	task.Render = input.Render
+	task.InputMode = input.InputMode
+	task.Classify = input.Classify
+	task.Split = input.Split
+	task.BatchID = input.BatchID
+	task.CacheBypass = input.CacheBypass
+	if err := task.Validate(); err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+	if err := uc.sealPassword(task, input.Password); err != nil {
+		return nil, err
+	}
+
+	if err := uc.taskRepo.Create(ctx, task); err != nil {
+		uc.logger.Error("Failed to save task to database",
+			zap.String("task_id", task.ID.String()),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to save task: %w", err)
+	}
+
+	uc.enqueue(ctx, task)
+
+	uc.logger.Info("Task created from URL",
+		zap.String("task_id", task.ID.String()),
+		zap.String("source_host", sourceURL.Host),
+	)
+
+	return task, nil
+}
+
+// CreateUpload выдаёт presigned URL для загрузки файла напрямую в хранилище
+func (uc *TaskUseCase) CreateUpload(ctx context.Context, fileName string) (*domain.PresignedUpload, error) {
+	if _, err := domain.ContentTypeFromFileName(fileName); err != nil {
+		return nil, err
+	}
+
+	upload, err := uc.fileStorage.PresignUpload(ctx, fileName, uc.upload.PresignTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	uc.logger.Debug("Presigned upload created",
+		zap.String("file_key", upload.FileKey),
+		zap.Time("expires_at", upload.ExpiresAt),
+	)
+
+	return upload, nil
+}
+
+// CreateFromUpload создаёт задачу по файлу, загруженному по presigned URL
+func (uc *TaskUseCase) CreateFromUpload(ctx context.Context, input CreateTaskFromUploadInput) (*domain.Task, error) {
+	// Принимаем только ключи прямой загрузки, чтобы нельзя было сослаться на чужой файл
+	if !domain.IsUploadKey(input.FileKey) {
+		return nil, domain.ErrInvalidUploadKey
+	}
+
+	info, err := uc.fileStorage.Stat(ctx, input.FileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size > uc.upload.MaxSize {
+		_ = uc.fileStorage.Delete(ctx, input.FileKey)
+		return nil, fmt.Errorf("%w: %d bytes", domain.ErrFileTooLarge, info.Size)
+	}
+
+	// Клиент может загрузить файл с произвольным Content-Type, поэтому при
+	// неподдерживаемом типе доверяем расширению файла
+	fileName := path.Base(input.FileKey)
+	contentType := info.ContentType
+	if domain.ValidateContentType(contentType) != nil {
+		contentType, err = domain.ContentTypeFromFileName(fileName)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	task, err := domain.NewTask(input.FileKey, fileName, contentType, input.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+	task.Template = input.Template
+	task.Tenant = input.Tenant
+	task.Render = input.Render
+	task.InputMode = input.InputMode
+	task.Classify = input.Classify
+	task.Split = input.Split
+	task.BatchID = input.BatchID
+	task.CacheBypass = input.CacheBypass
+	if err := task.Validate(); err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+	if err := uc.sealPassword(task, input.Password); err != nil {
+		return nil, err
+	}
+
+	// Файл прямой загрузки принадлежит одной задаче: удаление задачи или политика
+	// хранения удаляет его, не проверяя ссылки других задач
+	if err := uc.taskRepo.CreateWithFreeFile(ctx, task); err != nil {
+		if errors.Is(err, domain.ErrFileKeyInUse) {
+			return nil, err
+		}
+		uc.logger.Error("Failed to save task to database",
+			zap.String("task_id", task.ID.String()),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("failed to save task: %w", err)
+	}
+
+	uc.enqueue(ctx, task)
+
+	uc.logger.Info("Task created from direct upload",
+		zap.String("task_id", task.ID.String()),
+		zap.String("file_key", input.FileKey),
+	)
+
+	return task, nil
+}
+
+// sealPassword шифрует пароль документа для хранения в задаче
+func (uc *TaskUseCase) sealPassword(task *domain.Task, password string) error {
+	if password == "" {
+		return nil
+	}
+	if uc.secrets == nil {
+		return domain.ErrPasswordsDisabled
+	}
+
+	sealed, err := uc.secrets.Seal([]byte(password))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt password: %w", err)
+	}
+	task.Password = sealed
+	return nil
+}
+
+// enqueue ставит задачу в очередь. Ошибка не возвращается —
+// задача уже создана, её можно поставить в очередь повторно
+func (uc *TaskUseCase) enqueue(ctx context.Context, task *domain.Task) {
+	if err := uc.taskQueue.Enqueue(ctx, task.ID); err != nil {
+		uc.logger.Error("Failed to enqueue task",
+			zap.String("task_id", task.ID.String()),
+			zap.Error(err),
+		)
+	}
+}
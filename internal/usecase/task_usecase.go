@@ -2,7 +2,11 @@ package usecase
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 
 	"github.com/google/uuid"
 	"github.com/plastinin/docrecognizer/internal/domain"
@@ -14,6 +18,8 @@ type TaskUseCase struct {
 	taskRepo    TaskRepository
 	fileStorage FileStorage
 	taskQueue   TaskQueue
+	secrets     SecretSealer // nil — пароли документов не принимаются
+	upload      UploadOptions
 	logger      *zap.Logger
 }
 
@@ -22,50 +28,142 @@ func NewTaskUseCase(
 	taskRepo TaskRepository,
 	fileStorage FileStorage,
 	taskQueue TaskQueue,
+	secrets SecretSealer,
+	upload UploadOptions,
 	logger *zap.Logger,
 ) *TaskUseCase {
 	return &TaskUseCase{
 		taskRepo:    taskRepo,
 		fileStorage: fileStorage,
 		taskQueue:   taskQueue,
+		secrets:     secrets,
+		upload:      upload,
 		logger:      logger,
 	}
 }
 
 // Create создаёт новую задачу на распознавание
-func (uc *TaskUseCase) Create(ctx context.Context, input CreateTaskInput) (*domain.Task, error) {
+func (uc *TaskUseCase) Create(ctx context.Context, input CreateTaskInput) (*CreateTaskOutput, error) {
+	file := input.File
+	if file == nil {
+		uploaded, err := uc.UploadFile(ctx, input.FileName, input.ContentType, input.FileReader, input.FileSize)
+		if err != nil {
+			return nil, err
+		}
+		file = uploaded
+	}
+
+	output, err := uc.createWithFile(ctx, input, file)
+	if err != nil {
+		// Удаляем загруженный файл при ошибке
+		uc.DiscardFile(ctx, file)
+		return nil, err
+	}
+	return output, nil
+}
+
+// UploadFile загружает файл в хранилище, попутно считая размер и SHA-256 содержимого.
+// size равен -1, если размер заранее неизвестен (потоковая загрузка)
+func (uc *TaskUseCase) UploadFile(ctx context.Context, fileName, contentType string, reader io.Reader, size int64) (*UploadedFile, error) {
 	// Валидируем тип файла
-	if err := domain.ValidateContentType(input.ContentType); err != nil {
+	if err := domain.ValidateContentType(contentType); err != nil {
 		return nil, fmt.Errorf("validation error: %w", err)
 	}
 
-	// Загружаем файл в S3
-	fileKey, err := uc.fileStorage.Upload(ctx, input.FileName, input.ContentType, input.FileReader, input.FileSize)
+	hasher := sha256.New()
+	counter := &countingWriter{}
+	fileKey, err := uc.fileStorage.Upload(ctx, fileName, contentType, io.TeeReader(reader, io.MultiWriter(hasher, counter)), size)
 	if err != nil {
 		uc.logger.Error("Failed to upload file to storage",
-			zap.String("file_name", input.FileName),
+			zap.String("file_name", fileName),
 			zap.Error(err),
 		)
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
 
+	file := &UploadedFile{
+		Key:  fileKey,
+		Size: counter.n,
+		Hash: hex.EncodeToString(hasher.Sum(nil)),
+	}
+
 	uc.logger.Debug("File uploaded to storage",
-		zap.String("file_key", fileKey),
-		zap.String("file_name", input.FileName),
+		zap.String("file_key", file.Key),
+		zap.String("file_name", fileName),
+		zap.Int64("file_size", file.Size),
+		zap.String("file_hash", file.Hash),
 	)
 
+	return file, nil
+}
+
+// DiscardFile удаляет загруженный файл, для которого не была создана задача
+func (uc *TaskUseCase) DiscardFile(ctx context.Context, file *UploadedFile) {
+	if err := uc.fileStorage.Delete(ctx, file.Key); err != nil {
+		uc.logger.Warn("Failed to delete discarded file",
+			zap.String("file_key", file.Key),
+			zap.Error(err),
+		)
+	}
+}
+
+// createWithFile создаёт задачу для загруженного файла. При ошибке файл удаляет
+// вызывающий, в режиме reuse копия файла удаляется здесь же
+func (uc *TaskUseCase) createWithFile(ctx context.Context, input CreateTaskInput, file *UploadedFile) (*CreateTaskOutput, error) {
+	fileKey, fileHash := file.Key, file.Hash
+
 	// Создаём задачу
 	task, err := domain.NewTask(fileKey, input.FileName, input.ContentType, input.Schema)
 	if err != nil {
-		// Удаляем загруженный файл при ошибке
-		_ = uc.fileStorage.Delete(ctx, fileKey)
 		return nil, fmt.Errorf("failed to create task: %w", err)
 	}
+	task.Template = input.Template
+	task.Tenant = input.Tenant
+	task.Render = input.Render
+	task.InputMode = input.InputMode
+	task.Classify = input.Classify
+	task.Split = input.Split
+	task.BatchID = input.BatchID
+	task.FileHash = fileHash
+	task.CacheBypass = input.CacheBypass
+	if err := task.Validate(); err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+
+	// Ищем готовый результат для того же файла с теми же параметрами распознавания.
+	// Результат разделения — дочерние задачи, его не переиспользуем
+	var source *domain.Task
+	if input.Dedupe != "" && input.Dedupe != domain.DedupeModeOff && task.Split == "" {
+		source, err = uc.findDuplicate(ctx, task)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if source != nil && input.Dedupe == domain.DedupeModeReuse {
+		// Копия файла не нужна — возвращаем существующую задачу
+		_ = uc.fileStorage.Delete(ctx, fileKey)
+
+		uc.logger.Info("Duplicate document, existing task reused",
+			zap.String("task_id", source.ID.String()),
+			zap.String("file_hash", fileHash),
+		)
+		return &CreateTaskOutput{Task: source, Deduplicated: true}, nil
+	}
+
+	if err := uc.sealPassword(task, input.Password); err != nil {
+		return nil, err
+	}
+
+	// В режиме link задача сразу завершается готовым результатом
+	if source != nil {
+		if err := task.LinkResult(source); err != nil {
+			return nil, fmt.Errorf("failed to link task result: %w", err)
+		}
+	}
 
 	// Сохраняем задачу в БД
 	if err := uc.taskRepo.Create(ctx, task); err != nil {
-		// Удаляем загруженный файл при ошибке
-		_ = uc.fileStorage.Delete(ctx, fileKey)
 		uc.logger.Error("Failed to save task to database",
 			zap.String("task_id", task.ID.String()),
 			zap.Error(err),
@@ -73,22 +171,36 @@ func (uc *TaskUseCase) Create(ctx context.Context, input CreateTaskInput) (*doma
 		return nil, fmt.Errorf("failed to save task: %w", err)
 	}
 
-	// Добавляем задачу в очередь
-	if err := uc.taskQueue.Enqueue(ctx, task.ID); err != nil {
-		uc.logger.Error("Failed to enqueue task",
+	if task.DuplicateOf != nil {
+		uc.logger.Info("Duplicate document, task linked to existing result",
 			zap.String("task_id", task.ID.String()),
-			zap.Error(err),
+			zap.String("duplicate_of", task.DuplicateOf.String()),
 		)
-		// Не возвращаем ошибку — задача создана, можно retry позже
+		return &CreateTaskOutput{Task: task}, nil
 	}
 
+	// Добавляем задачу в очередь
+	uc.enqueue(ctx, task)
+
 	uc.logger.Info("Task created successfully",
 		zap.String("task_id", task.ID.String()),
 		zap.String("file_name", input.FileName),
 		zap.Strings("schema", input.Schema),
 	)
 
-	return task, nil
+	return &CreateTaskOutput{Task: task}, nil
+}
+
+// findDuplicate ищет завершённую задачу с тем же содержимым и параметрами распознавания
+func (uc *TaskUseCase) findDuplicate(ctx context.Context, task *domain.Task) (*domain.Task, error) {
+	source, err := uc.taskRepo.FindCompletedByHash(ctx, task)
+	if err != nil {
+		if errors.Is(err, domain.ErrTaskNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find duplicate task: %w", err)
+	}
+	return source, nil
 }
 
 // GetByID возвращает задачу по ID
@@ -113,14 +225,20 @@ func (uc *TaskUseCase) Delete(ctx context.Context, id uuid.UUID) error {
 		return err
 	}
 
-	// Удаляем файл из S3
-	if err := uc.fileStorage.Delete(ctx, task.FileKey); err != nil {
-		uc.logger.Warn("Failed to delete file from storage",
-			zap.String("task_id", id.String()),
-			zap.String("file_key", task.FileKey),
-			zap.Error(err),
-		)
-		// Продолжаем удаление задачи
+	// Удаляем файл и изображения страниц из S3
+	keys, err := ownedKeys(ctx, uc.taskRepo, task)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := uc.fileStorage.Delete(ctx, key); err != nil {
+			uc.logger.Warn("Failed to delete file from storage",
+				zap.String("task_id", id.String()),
+				zap.String("file_key", key),
+				zap.Error(err),
+			)
+			// Продолжаем удаление задачи
+		}
 	}
 
 	// Удаляем задачу из БД
@@ -133,4 +251,14 @@ func (uc *TaskUseCase) Delete(ctx context.Context, id uuid.UUID) error {
 	)
 
 	return nil
-}
\ No newline at end of file
+}
+
+// countingWriter считает количество записанных байт
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_tasks_result_fts;
+DROP INDEX IF EXISTS idx_tasks_result;
+DROP INDEX IF EXISTS idx_tasks_updated_at_id;
+DROP INDEX IF EXISTS idx_tasks_created_at_id;
+DROP INDEX IF EXISTS idx_tasks_file_name_trgm;
+DROP INDEX IF EXISTS idx_tasks_content_type;
+DROP INDEX IF EXISTS idx_tasks_completed_at;
+DROP INDEX IF EXISTS idx_tasks_batch_id;
+DROP INDEX IF EXISTS idx_tasks_template;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS batch_id;
+ALTER TABLE tasks DROP COLUMN IF EXISTS template;
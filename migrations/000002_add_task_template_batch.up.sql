@@ -0,0 +1,24 @@
+-- Расширение для поиска по подстроке имени файла
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Шаблон документа и пакет загрузки
+ALTER TABLE tasks ADD COLUMN template VARCHAR(100);
+ALTER TABLE tasks ADD COLUMN batch_id UUID;
+
+-- Индексы для фильтрации списка задач
+CREATE INDEX idx_tasks_template ON tasks(template) WHERE template IS NOT NULL;
+CREATE INDEX idx_tasks_batch_id ON tasks(batch_id) WHERE batch_id IS NOT NULL;
+CREATE INDEX idx_tasks_completed_at ON tasks(completed_at DESC);
+CREATE INDEX idx_tasks_content_type ON tasks(content_type);
+CREATE INDEX idx_tasks_file_name_trgm ON tasks USING GIN (file_name gin_trgm_ops);
+
+-- Индексы для keyset пагинации
+CREATE INDEX idx_tasks_created_at_id ON tasks(created_at DESC, id DESC);
+CREATE INDEX idx_tasks_updated_at_id ON tasks(updated_at DESC, id DESC);
+
+-- Индексы для поиска внутри результата
+CREATE INDEX idx_tasks_result ON tasks USING GIN (result jsonb_path_ops);
+CREATE INDEX idx_tasks_result_fts ON tasks USING GIN (to_tsvector('simple', COALESCE(result::text, '')));
+
+COMMENT ON COLUMN tasks.template IS 'Шаблон (тип) документа';
+COMMENT ON COLUMN tasks.batch_id IS 'Идентификатор пакета загрузки';
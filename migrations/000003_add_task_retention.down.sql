@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS retention_audit;
+
+DROP INDEX IF EXISTS idx_tasks_retention;
+
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS result_purged_at,
+    DROP COLUMN IF EXISTS file_deleted_at;
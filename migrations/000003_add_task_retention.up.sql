@@ -0,0 +1,25 @@
+ALTER TABLE tasks
+    ADD COLUMN file_deleted_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN result_purged_at TIMESTAMP WITH TIME ZONE;
+
+-- Поиск задач с истёкшим сроком хранения
+CREATE INDEX idx_tasks_retention ON tasks(status, (COALESCE(completed_at, created_at)));
+
+-- Журнал удалений по политикам хранения
+CREATE TABLE retention_audit (
+    id              BIGSERIAL PRIMARY KEY,
+    task_id         UUID NOT NULL,
+    action          VARCHAR(20) NOT NULL,
+    policy          VARCHAR(100) NOT NULL,
+    file_key        VARCHAR(512) NOT NULL,
+    task_status     task_status NOT NULL,
+    task_created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    executed_at     TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_retention_audit_task_id ON retention_audit(task_id);
+CREATE INDEX idx_retention_audit_executed_at ON retention_audit(executed_at DESC);
+
+COMMENT ON COLUMN tasks.file_deleted_at IS 'Время удаления исходного файла по политике хранения';
+COMMENT ON COLUMN tasks.result_purged_at IS 'Время удаления результата по политике хранения';
+COMMENT ON TABLE retention_audit IS 'Журнал удалений по политикам хранения';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_tasks_file_hash;
+
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS duplicate_of,
+    DROP COLUMN IF EXISTS file_hash;
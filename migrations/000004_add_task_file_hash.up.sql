@@ -0,0 +1,9 @@
+ALTER TABLE tasks
+    ADD COLUMN file_hash CHAR(64),
+    ADD COLUMN duplicate_of UUID REFERENCES tasks(id) ON DELETE SET NULL;
+
+-- Поиск завершённых задач с тем же содержимым файла
+CREATE INDEX idx_tasks_file_hash ON tasks(file_hash) WHERE status = 'completed';
+
+COMMENT ON COLUMN tasks.file_hash IS 'SHA-256 содержимого файла (hex)';
+COMMENT ON COLUMN tasks.duplicate_of IS 'Задача, чей результат переиспользован при дедупликации';
@@ -0,0 +1,3 @@
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS cache_status,
+    DROP COLUMN IF EXISTS cache_bypass;
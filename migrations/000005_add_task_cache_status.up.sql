@@ -0,0 +1,6 @@
+ALTER TABLE tasks
+    ADD COLUMN cache_bypass BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN cache_status VARCHAR(10);
+
+COMMENT ON COLUMN tasks.cache_bypass IS 'Не брать результат из кэша распознавания';
+COMMENT ON COLUMN tasks.cache_status IS 'Результат обращения к кэшу: hit, miss, bypass';
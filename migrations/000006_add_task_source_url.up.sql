@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN source_url TEXT;
+
+COMMENT ON COLUMN tasks.source_url IS 'URL, с которого воркер скачивает документ';
@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN page_keys TEXT[];
+
+COMMENT ON COLUMN tasks.page_keys IS 'Ключи изображений страниц, отправленных в модель';
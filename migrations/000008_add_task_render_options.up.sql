@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN render_options JSONB;
+
+COMMENT ON COLUMN tasks.render_options IS 'Параметры рендеринга PDF: dpi, pages, max_width, max_height';
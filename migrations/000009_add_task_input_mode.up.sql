@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN input_mode VARCHAR(10);
+
+COMMENT ON COLUMN tasks.input_mode IS 'Входные данные модели для PDF: image, text, hybrid';
@@ -0,0 +1,6 @@
+ALTER TABLE tasks
+    ADD COLUMN password BYTEA,
+    ADD COLUMN error_code VARCHAR(50);
+
+COMMENT ON COLUMN tasks.password IS 'Пароль документа, зашифрованный мастер-ключом; удаляется после успешной обработки';
+COMMENT ON COLUMN tasks.error_code IS 'Код ошибки обработки для клиента, например pdf_password_required';
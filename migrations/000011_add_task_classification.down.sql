@@ -0,0 +1,4 @@
+ALTER TABLE tasks
+    DROP COLUMN IF EXISTS classification_confidence,
+    DROP COLUMN IF EXISTS document_type,
+    DROP COLUMN IF EXISTS classify;
@@ -0,0 +1,8 @@
+ALTER TABLE tasks
+    ADD COLUMN classify BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN document_type VARCHAR(100),
+    ADD COLUMN classification_confidence REAL;
+
+COMMENT ON COLUMN tasks.classify IS 'Определить тип документа моделью перед извлечением полей';
+COMMENT ON COLUMN tasks.document_type IS 'Тип документа (имя шаблона), предсказанный классификацией';
+COMMENT ON COLUMN tasks.classification_confidence IS 'Уверенность классификации от 0 до 1';
@@ -0,0 +1,9 @@
+ALTER TABLE tasks
+    ADD COLUMN split_mode VARCHAR(20),
+    ADD COLUMN parent_id UUID REFERENCES tasks(id) ON DELETE SET NULL;
+
+-- Документы, выделенные из файла родительской задачи
+CREATE INDEX idx_tasks_parent_id ON tasks(parent_id) WHERE parent_id IS NOT NULL;
+
+COMMENT ON COLUMN tasks.split_mode IS 'Разделение файла на документы: blank или classify';
+COMMENT ON COLUMN tasks.parent_id IS 'Задача, из файла которой выделен документ';
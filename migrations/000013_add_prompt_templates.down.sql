@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS prompt_templates;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS prompt_version;
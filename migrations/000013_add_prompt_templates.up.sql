@@ -0,0 +1,14 @@
+ALTER TABLE tasks ADD COLUMN prompt_version VARCHAR(100);
+
+COMMENT ON COLUMN tasks.prompt_version IS 'Версия промпта, с которым распознан документ';
+
+-- Промпты модели в формате Go text/template, заменяющие встроенные
+CREATE TABLE prompt_templates (
+    name VARCHAR(100) PRIMARY KEY,
+    body TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE prompt_templates IS 'Промпты модели, загружаемые воркером при старте';
+COMMENT ON COLUMN prompt_templates.name IS 'recognize, classify или имя типа документа';
+COMMENT ON COLUMN prompt_templates.body IS 'Текст промпта в формате Go text/template';
@@ -0,0 +1,2 @@
+-- Проверка ссылок на файл, общий для родительской и дочерних задач разделённого документа
+CREATE INDEX idx_tasks_file_key ON tasks(file_key) WHERE file_deleted_at IS NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE retention_audit
+    DROP COLUMN IF EXISTS tenant,
+    ALTER COLUMN policy TYPE VARCHAR(100) USING left(policy, 100);
+
+DROP INDEX IF EXISTS idx_tasks_tenant_retention;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS tenant;
@@ -0,0 +1,10 @@
+ALTER TABLE tasks ADD COLUMN tenant VARCHAR(64);
+
+-- Политики хранения и фильтр списка по арендатору
+CREATE INDEX idx_tasks_tenant_retention ON tasks(tenant, status, (COALESCE(completed_at, created_at))) WHERE tenant IS NOT NULL;
+
+ALTER TABLE retention_audit
+    ADD COLUMN tenant VARCHAR(64),
+    ALTER COLUMN policy TYPE VARCHAR(200);
+
+COMMENT ON COLUMN tasks.tenant IS 'Арендатор, которому принадлежит документ';
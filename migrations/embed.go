@@ -0,0 +1,9 @@
+// Package migrations содержит SQL миграции базы данных, встроенные в бинарники
+package migrations
+
+import "embed"
+
+// FS файлы миграций в формате golang-migrate: NNNNNN_name.up.sql / NNNNNN_name.down.sql
+//
+//go:embed *.sql
+var FS embed.FS
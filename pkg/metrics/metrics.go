@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "docrecognizer"
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Количество HTTP запросов по маршруту и статусу",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Длительность обработки HTTP запросов",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	tasksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "tasks",
+		Name:      "processed_total",
+		Help:      "Количество обработанных задач по финальному статусу",
+	}, []string{"status"})
+
+	taskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "tasks",
+		Name:      "duration_seconds",
+		Help:      "Время от создания задачи до финального статуса",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1200},
+	}, []string{"status"})
+
+	llmRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "request_duration_seconds",
+		Help:      "Длительность запросов к LLM",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600},
+	}, []string{"model", "status"})
+
+	llmTokensTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "llm",
+		Name:      "tokens_total",
+		Help:      "Количество токенов LLM по модели и типу (prompt, completion)",
+	}, []string{"model", "type"})
+
+	pdfRenderDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "pdf",
+		Name:      "render_duration_seconds",
+		Help:      "Длительность рендеринга страницы PDF",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	})
+
+	storageOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "storage",
+		Name:      "operation_duration_seconds",
+		Help:      "Длительность операций с файловым хранилищем",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "status"})
+
+	recognitionCacheTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "recognition_cache",
+		Name:      "requests_total",
+		Help:      "Обращения к кэшу распознавания по результату (hit, miss, bypass)",
+	}, []string{"status"})
+
+	retentionActionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "retention",
+		Name:      "actions_total",
+		Help:      "Количество задач, обработанных политиками хранения, по действию",
+	}, []string{"action"})
+)
+
+// Handler возвращает HTTP обработчик для /metrics
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// MustRegister регистрирует дополнительные коллекторы
+func MustRegister(collectors ...prometheus.Collector) {
+	prometheus.MustRegister(collectors...)
+}
+
+// ObserveHTTPRequest учитывает обработанный HTTP запрос
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	statusStr := strconv.Itoa(status)
+	httpRequestsTotal.WithLabelValues(method, route, statusStr).Inc()
+	httpRequestDuration.WithLabelValues(method, route, statusStr).Observe(duration.Seconds())
+}
+
+// ObserveTask учитывает задачу, перешедшую в финальный статус
+func ObserveTask(status string, duration time.Duration) {
+	tasksTotal.WithLabelValues(status).Inc()
+	taskDuration.WithLabelValues(status).Observe(duration.Seconds())
+}
+
+// ObserveLLMRequest учитывает запрос к LLM
+func ObserveLLMRequest(model string, duration time.Duration, err error) {
+	llmRequestDuration.WithLabelValues(model, statusLabel(err)).Observe(duration.Seconds())
+}
+
+// AddLLMTokens учитывает израсходованные токены
+func AddLLMTokens(model string, promptTokens, completionTokens int) {
+	llmTokensTotal.WithLabelValues(model, "prompt").Add(float64(promptTokens))
+	llmTokensTotal.WithLabelValues(model, "completion").Add(float64(completionTokens))
+}
+
+// ObservePDFRender учитывает рендеринг страницы PDF
+func ObservePDFRender(duration time.Duration) {
+	pdfRenderDuration.Observe(duration.Seconds())
+}
+
+// ObserveStorageOperation учитывает операцию с хранилищем, начатую в start
+func ObserveStorageOperation(operation string, start time.Time, err error) {
+	storageOperationDuration.WithLabelValues(operation, statusLabel(err)).Observe(time.Since(start).Seconds())
+}
+
+// ObserveRecognitionCache учитывает обращение к кэшу распознавания
+func ObserveRecognitionCache(status string) {
+	recognitionCacheTotal.WithLabelValues(status).Inc()
+}
+
+// ObserveRetentionAction учитывает действие политики хранения над задачей
+func ObserveRetentionAction(action string) {
+	retentionActionsTotal.WithLabelValues(action).Inc()
+}
+
+func statusLabel(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}